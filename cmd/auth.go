@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// tokenEnvVars are the environment variables checked for a token, in order
+var tokenEnvVars = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+
+// defaultGHHost is the host gh uses when it is not configured for another one
+const defaultGHHost = "github.com"
+
+// resolveToken fills in token when --token was not given, first from the
+// environment and then from the gh CLI's stored credentials. Without
+// --base-url, gh's credentials are those of gh's default host, and a GitHub
+// Enterprise Server default host also becomes the base URL. It returns a
+// description of where the token came from, or an empty string if no token
+// was found.
+func resolveToken() string {
+	if token != "" {
+		return "--token flag"
+	}
+
+	for _, name := range tokenEnvVars {
+		if value := os.Getenv(name); value != "" {
+			token = value
+			return name + " environment variable"
+		}
+	}
+
+	host, hostSource := ghHost()
+	if value, source := auth.TokenForHost(host); value != "" {
+		token = value
+		if baseURL == "" && host != defaultGHHost {
+			baseURL = "https://" + host + "/"
+			logger.Info("Using gh's GitHub host", slog.String("host", host), slog.String("source", hostSource))
+		}
+		// gh also reads GH_ENTERPRISE_TOKEN and GITHUB_ENTERPRISE_TOKEN for
+		// Enterprise Server hosts
+		if strings.HasSuffix(source, "_TOKEN") {
+			return source + " environment variable"
+		}
+		return "gh CLI credentials"
+	}
+
+	return ""
+}
+
+// ghHost returns the host whose gh credentials are used and where it came
+// from: the --base-url host if set, and otherwise the host gh uses by
+// default, as reported by go-gh. That is $GH_HOST, or the only host gh is
+// logged in to, or github.com
+func ghHost() (string, string) {
+	if baseURL != "" {
+		if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+			return u.Host, "--base-url"
+		}
+	}
+
+	host, source := auth.DefaultHost()
+	if source == "GH_HOST" {
+		return host, "$GH_HOST"
+	}
+	return host, "gh CLI config"
+}
+
+// privateKeyEnvVar is the environment variable holding the GitHub App private
+// key when --private-key is not set
+const privateKeyEnvVar = "GITHUB_APP_PRIVATE_KEY"
+
+// usingApp reports whether GitHub App authentication was requested
+func usingApp() bool {
+	return appID != 0 || installationID != 0
+}
+
+// resolveAppAuth loads the GitHub App credentials from the --app-id,
+// --installation-id and --private-key flags, reading the private key from
+// the environment when --private-key is not set
+func resolveAppAuth() (*codeql.AppAuth, error) {
+	if appID <= 0 || installationID <= 0 {
+		return nil, fmt.Errorf("--app-id and --installation-id must both be set to authenticate as a GitHub App")
+	}
+
+	var key []byte
+	if privateKeyFile != "" {
+		var err error
+		if key, err = os.ReadFile(privateKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+		}
+	} else if value := os.Getenv(privateKeyEnvVar); value != "" {
+		key = []byte(value)
+	} else {
+		return nil, fmt.Errorf("--private-key or $%s is required to authenticate as a GitHub App", privateKeyEnvVar)
+	}
+
+	return &codeql.AppAuth{AppID: appID, InstallationID: installationID, PrivateKey: key}, nil
+}
+
+// resolveCredentials sets up GitHub App authentication when the app flags are
+// set, logs in with --login unless --token is given, and otherwise resolves
+// the token. It reports whether a token was found
+func resolveCredentials() (bool, error) {
+	if usingApp() {
+		if login {
+			return false, fmt.Errorf("--login cannot be used with --app-id")
+		}
+		auth, err := resolveAppAuth()
+		if err != nil {
+			return false, err
+		}
+		app = auth
+		return true, nil
+	}
+
+	if login && token == "" {
+		source, err := loginToken()
+		if err != nil {
+			return false, err
+		}
+		logger.Info("Using GitHub token", slog.String("source", source))
+		return true, nil
+	}
+
+	source := resolveToken()
+	if source == "" {
+		return false, nil
+	}
+	logger.Info("Using GitHub token", slog.String("source", source))
+	return true, nil
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+	jsonpkg "github.com/lindluni/gh-generate-codeql-report/pkg/json"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/report"
+)
+
+// compareFormats are the output formats of the compare command
+var compareFormats = []string{"text", "csv", "json"}
+
+var (
+	// Output format and file of the compare command
+	compareFormat string
+	compareOutput string
+)
+
+// compareCmd compares two reports written by earlier runs
+var compareCmd = &cobra.Command{
+	Use:   "compare OLD_REPORT NEW_REPORT",
+	Short: "Compare two CSV or JSON reports",
+	Long: `Compare the alerts of two CSV or JSON reports written by earlier runs and
+list the alerts that were added, removed or changed, matched by repository
+and alert number. No API calls are made.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			return withExitCode(exitBadInput, err)
+		}
+		return nil
+	},
+	// Only report files are read, so the config file of the report command
+	// does not apply
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		setupLogging()
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !slices.Contains(compareFormats, compareFormat) {
+			return withExitCode(exitBadInput, fmt.Errorf("invalid format %q: must be one of %s", compareFormat, strings.Join(compareFormats, ", ")))
+		}
+		sep, err := parseDelimiter(delimiter)
+		if err != nil {
+			return withExitCode(exitBadInput, err)
+		}
+
+		diff, err := report.CompareReports(args[0], args[1], csvpkg.Options{Delimiter: sep})
+		if err != nil {
+			return withExitCode(exitBadInput, err)
+		}
+		logger.Info("Compared reports", slog.String("old", args[0]), slog.String("new", args[1]),
+			slog.Int("added", len(diff.Added)), slog.Int("removed", len(diff.Removed)), slog.Int("changed", len(diff.Changed)))
+
+		return writeDiff(args[0], args[1], diff)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVar(&compareFormat, "format", "text", "Output format of the comparison ("+strings.Join(compareFormats, ", ")+")")
+	compareCmd.Flags().StringVar(&compareOutput, "output", stdoutOutput, "Path to write the comparison to (\"-\" for stdout)")
+}
+
+// writeDiff writes the comparison of the reports at oldPath and newPath to
+// --output in the --format of the compare command
+func writeDiff(oldPath, newPath string, diff *report.Diff) error {
+	out := io.Writer(os.Stdout)
+	if compareOutput != stdoutOutput {
+		f, err := os.Create(compareOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create file %s: %w", compareOutput, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var err error
+	switch compareFormat {
+	case "csv":
+		err = csvpkg.Encode(out, diffHeaders, diffRecords(diff), csvpkg.Options{EscapeFormulas: escapeFormulas})
+	case "json":
+		err = jsonpkg.Encode(out, diff)
+	default:
+		err = writeDiffSummary(out, oldPath, newPath, diff)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write comparison: %w", err)
+	}
+
+	if f, ok := out.(*os.File); ok && f != os.Stdout {
+		return f.Close()
+	}
+	return nil
+}
+
+// diffHeaders are the columns of a comparison written as CSV
+var diffHeaders = []string{"Change", "Org", "Repo", "Alert ID", "Severity", "Rule ID", "File Path", "Field", "Old Value", "New Value"}
+
+// diffRecords returns a row for each added and removed alert, and one for
+// each changed field of a changed alert
+func diffRecords(diff *report.Diff) [][]string {
+	row := func(change string, entry report.DiffEntry) []string {
+		return []string{change, entry.Owner, entry.Repo, fmt.Sprint(entry.ID),
+			entry.Fields["Severity"], entry.Fields["RuleID"], entry.Fields["FilePath"]}
+	}
+
+	var records [][]string
+	for _, entry := range diff.Added {
+		records = append(records, append(row(report.ChangeAdded, entry), "", "", ""))
+	}
+	for _, entry := range diff.Removed {
+		records = append(records, append(row(report.ChangeRemoved, entry), "", "", ""))
+	}
+	for _, entry := range diff.Changed {
+		for _, change := range entry.Changes {
+			records = append(records, append(row(report.ChangeChanged, entry), change.Field, change.Old, change.New))
+		}
+	}
+	return records
+}
+
+// writeDiffSummary writes the comparison as text meant to be read by people
+func writeDiffSummary(out io.Writer, oldPath, newPath string, diff *report.Diff) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Compared %s (%d alerts) with %s (%d alerts)\n", oldPath, diff.OldAlerts, newPath, diff.NewAlerts)
+	fmt.Fprintf(&b, "%d added, %d removed, %d changed, %d unchanged\n",
+		len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+
+	sections := []struct {
+		title   string
+		entries []report.DiffEntry
+	}{
+		{"Added", diff.Added},
+		{"Removed", diff.Removed},
+		{"Changed", diff.Changed},
+	}
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		for _, entry := range section.entries {
+			fmt.Fprintf(&b, "  %s\n", describeDiffEntry(entry))
+			for _, change := range entry.Changes {
+				fmt.Fprintf(&b, "    %s: %q -> %q\n", change.Field, change.Old, change.New)
+			}
+		}
+	}
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// describeDiffEntry describes an alert on one line: its repository and
+// number, followed by its severity, rule and location if the reports have them
+func describeDiffEntry(entry report.DiffEntry) string {
+	parts := []string{fmt.Sprintf("%s/%s#%d", entry.Owner, entry.Repo, entry.ID)}
+	for _, field := range []string{"Severity", "RuleID"} {
+		if value := entry.Fields[field]; value != "" {
+			parts = append(parts, value)
+		}
+	}
+	if path := entry.Fields["FilePath"]; path != "" {
+		if line := entry.Fields["StartLine"]; line != "" {
+			path += ":" + line
+		}
+		parts = append(parts, path)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// configName is the directory under the user config directory, and the base
+// name, of the default config file
+const configName = "gh-generate-codeql-report"
+
+// configFile is the path of the config file given by --config
+var configFile string
+
+// loadConfig reads the config file, if any, and uses its values for every flag
+// not given on the command line, so command-line flags take precedence
+func loadConfig(flags *pflag.FlagSet) error {
+	v := viper.New()
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+	} else {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil
+		}
+		v.SetConfigName("config")
+		v.AddConfigPath(filepath.Join(dir, configName))
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		// Only an explicitly requested config file has to exist
+		var notFound viper.ConfigFileNotFoundError
+		if configFile == "" && errors.As(err, &notFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var applyErr error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if applyErr != nil || flag.Changed || !v.IsSet(flag.Name) {
+			return
+		}
+
+		var err error
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			err = slice.Replace(v.GetStringSlice(flag.Name))
+		} else if flag.Value.Type() == "stringToString" {
+			err = flag.Value.Set(joinMapping(v.GetStringMapString(flag.Name)))
+		} else {
+			err = flag.Value.Set(v.GetString(flag.Name))
+		}
+		if err != nil {
+			applyErr = fmt.Errorf("invalid value for %q in config file %s: %w", flag.Name, v.ConfigFileUsed(), err)
+			return
+		}
+
+		// Values from the config file are explicit settings, like flags
+		flag.Changed = true
+	})
+	if applyErr != nil {
+		return applyErr
+	}
+
+	// Catch typos, which would otherwise be silently ignored
+	for _, key := range v.AllKeys() {
+		// Keys of a map flag are nested under the flag name
+		name, _, _ := strings.Cut(key, ".")
+		if flags.Lookup(name) == nil {
+			fmt.Fprintf(os.Stderr, "Warning: unknown key %q in config file %s\n", key, v.ConfigFileUsed())
+		}
+	}
+
+	configFile = v.ConfigFileUsed()
+	return nil
+}
+
+// joinMapping formats a config file map as the key=value list accepted by map flags
+func joinMapping(mapping map[string]string) string {
+	pairs := make([]string, 0, len(mapping))
+	for key, value := range mapping {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
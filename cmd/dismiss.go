@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Dismissal flags
+	dismissReason  string
+	dismissComment string
+)
+
+// dismissCmd dismisses the alerts listed in the input CSV
+var dismissCmd = &cobra.Command{
+	Use:   "dismiss",
+	Short: "Dismiss the alerts listed in the input CSV",
+	Long: `Dismiss every CodeQL alert listed in the input CSV with the given reason
+and comment. The input uses the same format as the report command. Use
+--dry-run to list the alerts that would be dismissed without changing them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// A timeout of zero means the run is never cut short
+		cancel := func() {}
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		defer cancel()
+
+		if err := validateDismissFlags(); err != nil {
+			return withExitCode(exitBadInput, err)
+		}
+
+		return dismissAlerts(ctx)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(dismissCmd)
+
+	dismissCmd.Flags().StringVar(&dismissReason, "reason", "", "Reason for dismissing the alerts (\"false positive\", \"won't fix\", \"used in tests\")")
+	dismissCmd.Flags().StringVar(&dismissComment, "comment", "", "Comment recorded with each dismissal")
+}
+
+// validateDismissFlags checks the flags used by the dismiss command
+func validateDismissFlags() error {
+	if quiet && verbose {
+		return fmt.Errorf("--quiet and --verbose cannot be used together")
+	}
+
+	defaultURLListInput()
+	if len(inputFiles) == 0 {
+		return fmt.Errorf("required flag(s) not provided: input")
+	}
+
+	if err := validateInputFiles(); err != nil {
+		return err
+	}
+
+	if listMode() {
+		return fmt.Errorf("--repo, --org and --repos-file cannot be used with dismiss")
+	}
+
+	if !slices.Contains(codeql.DismissReasons, dismissReason) {
+		return fmt.Errorf(`invalid reason %q: must be one of "false positive", "won't fix", "used in tests"`, dismissReason)
+	}
+
+	if _, err := parseDelimiter(delimiter); err != nil {
+		return err
+	}
+
+	if maxRetries < 0 {
+		return fmt.Errorf("--max-retries must not be negative")
+	}
+
+	if maxTotalRetries < 0 {
+		return fmt.Errorf("--max-total-retries must not be negative")
+	}
+
+	if maxAPICalls < 0 {
+		return fmt.Errorf("--max-api-calls must not be negative")
+	}
+
+	if requestTimeout < 0 {
+		return fmt.Errorf("--request-timeout must not be negative")
+	}
+
+	// The token is not needed when no API calls are made
+	if !dryRun {
+		found, err := resolveCredentials()
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("required flag(s) not provided: token")
+		}
+	}
+
+	return nil
+}
+
+// dismissAlerts dismisses each alert referenced by the input records, one at a
+// time, or only lists them with --dry-run
+func dismissAlerts(ctx context.Context) error {
+	cfg := newConfig()
+	records, skipped, err := report.ReadRecords(cfg)
+	if err != nil {
+		return withExitCode(exitBadInput, err)
+	}
+
+	refs, invalid := report.ParseRecords(cfg, records)
+	refs = report.DedupeRefs(refs, logger)
+	failed := skipped + len(invalid)
+
+	if dryRun {
+		if !quiet {
+			for _, ref := range refs {
+				fmt.Printf("Would dismiss %s as %q\n", ref, dismissReason)
+			}
+		}
+		logger.Info("Dry run: alerts would be dismissed", slog.Int("alerts", len(refs)))
+		if failed > 0 {
+			return withExitCode(exitBadInput, fmt.Errorf("%d records are malformed", failed))
+		}
+		return nil
+	}
+
+	client, err := report.NewClient(cfg)
+	if err != nil {
+		return withExitCode(exitBadInput, err)
+	}
+
+	if err := report.CheckToken(ctx, client); err != nil {
+		return err
+	}
+
+	// Dismiss alerts sequentially, as GitHub recommends for requests that change data
+	var dismissed int
+	for i, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return withExitCode(exitPartialFailure, fmt.Errorf("stopped after %d of %d alerts: %w", i, len(refs), err))
+		}
+
+		if _, err := client.DismissAlert(ctx, ref.Owner, ref.Repo, ref.Number, dismissReason, dismissComment); err != nil {
+			logger.Error("Failed to dismiss alert", append(codeql.AlertAttrs(ref.Owner, ref.Repo, ref.Number), slog.Any("error", err))...)
+			failed++
+			continue
+		}
+		dismissed++
+
+		if verbose {
+			fmt.Printf("Dismissed %s\n", ref)
+		}
+	}
+
+	logger.Info("Dismissed alerts", slog.Int("dismissed", dismissed), slog.Int("total", dismissed+failed))
+	if !quiet {
+		fmt.Printf("Dismissed %d of %d alerts\n", dismissed, dismissed+failed)
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case dismissed == 0:
+		return withExitCode(exitTotalFailure, fmt.Errorf("no alerts could be dismissed"))
+	default:
+		return withExitCode(exitPartialFailure, fmt.Errorf("%d alerts could not be dismissed", failed))
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/report"
+)
+
+// Exit codes returned by the tool
+const (
+	// exitSuccess means every alert was written to the report
+	exitSuccess = 0
+	// exitError means the run failed for an unexpected reason
+	exitError = 1
+	// exitPartialFailure means the report was written but some alerts failed
+	exitPartialFailure = 2
+	// exitTotalFailure means no alert could be fetched
+	exitTotalFailure = 3
+	// exitBadInput means the flags or input file were invalid
+	exitBadInput = 4
+	// exitSeverityGate means the report contains alerts at or above --fail-on-severity
+	exitSeverityGate = 5
+	// exitInterrupted means the run was stopped by SIGINT or SIGTERM
+	exitInterrupted = 130
+)
+
+// exitCodeError is an error that determines the process exit code
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+// withExitCode wraps err so the process exits with code
+func withExitCode(code int, err error) error {
+	return &exitCodeError{code: code, err: err}
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	// Invalid configuration or input, such as a rejected token
+	var inputErr *report.InputError
+	if errors.As(err, &inputErr) {
+		return exitBadInput
+	}
+	return exitError
+}
+
+// runExitCode returns the exit code for a report run from its result and
+// error. An exit code already attached to err takes precedence.
+func runExitCode(result *report.Result, err error) int {
+	var exitErr *exitCodeError
+	var inputErr *report.InputError
+	if errors.As(err, &exitErr) || errors.As(err, &inputErr) {
+		return exitCode(err)
+	}
+
+	// The run failed before any alert was processed
+	if result == nil {
+		return exitCode(err)
+	}
+
+	switch {
+	case result.Written == 0 && (result.Failed > 0 || err != nil):
+		return exitTotalFailure
+	case result.Failed > 0 || err != nil:
+		return exitPartialFailure
+	default:
+		return exitSuccess
+	}
+}
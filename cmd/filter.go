@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// parseSince parses --since as an RFC 3339 timestamp, a date, or a duration
+// before now such as "7d", "2w" or "36h"
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+
+	// time.ParseDuration has no units longer than hours
+	var d time.Duration
+	var err error
+	if n, unit, ok := cutUnit(value); ok {
+		d = time.Duration(n) * unit
+	} else if d, err = time.ParseDuration(value); err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: must be an RFC 3339 timestamp, a date (YYYY-MM-DD) or a duration such as 7d", value)
+	}
+	if d < 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q: duration must not be negative", value)
+	}
+	return now.Add(-d), nil
+}
+
+// cutUnit parses a whole number of days ("7d") or weeks ("2w")
+func cutUnit(value string) (int, time.Duration, bool) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, 0, false
+			}
+			return n, unit, true
+		}
+	}
+	return 0, 0, false
+}
+
+// validatePathFilters checks that every --path-filter is a valid glob
+func validatePathFilters() error {
+	for _, pattern := range pathFilters {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid --path-filter %q", pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"unicode/utf8"
+
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/report"
+)
+
+// csvOptions returns the CSV options configured by --delimiter, --lazy-quotes,
+// --skip-bad-rows and --escape-formulas. Values are always trimmed, as stray
+// spaces are common in spreadsheet exports.
+func csvOptions() csvpkg.Options {
+	// The delimiter has already been checked by validateFlags
+	comma, _ := parseDelimiter(delimiter)
+	return csvpkg.Options{
+		Delimiter:      comma,
+		LazyQuotes:     lazyQuotes,
+		SkipBadRows:    skipBadRows,
+		TrimValues:     true,
+		EscapeFormulas: escapeFormulas,
+	}
+}
+
+// validateInputFiles checks that every --input file exists, is a regular file
+// rather than a directory, and can be opened, so a mistyped path fails before
+// any work is done
+func validateInputFiles() error {
+	for _, inputFile := range inputFiles {
+		if inputFile == csvpkg.StdinPath {
+			continue
+		}
+
+		info, err := os.Stat(inputFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("input file not found: %s", inputFile)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check input file %s: %w", inputFile, err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("input file is a directory: %s", inputFile)
+		}
+
+		f, err := os.Open(inputFile)
+		if err != nil {
+			return fmt.Errorf("input file is not readable: %s: %w", inputFile, err)
+		}
+		f.Close()
+	}
+	return nil
+}
+
+// defaultURLListInput reads a --url-list from standard input when no --input
+// is given, so URLs can be piped in
+func defaultURLListInput() {
+	if urlList && len(inputFiles) == 0 && !listMode() {
+		inputFiles = []string{csvpkg.StdinPath}
+	}
+}
+
+// parseDelimiter converts the --delimiter value into a single rune.
+// "tab" and "\t" are accepted as aliases for a tab character.
+func parseDelimiter(value string) (rune, error) {
+	if value == "tab" || value == `\t` {
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(value)
+	if size == 0 || size != len(value) || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter %q: must be a single character", value)
+	}
+	if r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid delimiter %q", value)
+	}
+	return r, nil
+}
+
+// validateInput parses every input record without calling the API and
+// reports how many are valid. It returns an error if any record is malformed.
+func validateInput(cfg report.Config) error {
+	records, skipped, err := report.ReadRecords(cfg)
+	if err != nil {
+		return err
+	}
+
+	invalid := skipped
+	for _, record := range records {
+		if _, err := report.ParseRecord(cfg, record); err != nil {
+			logger.Info("Invalid record", slog.String("record", record.String()), slog.Any("error", err))
+			fmt.Fprintf(os.Stderr, "Invalid %s: %v\n", record, err)
+			invalid++
+		}
+	}
+
+	total := len(records) + skipped
+	valid := total - invalid
+	logger.Info("Dry run: validated records", slog.Int("valid", valid), slog.Int("malformed", invalid))
+	if !quiet {
+		fmt.Printf("Dry run: %d valid, %d malformed records\n", valid, invalid)
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d records are malformed", invalid, total)
+	}
+	return nil
+}
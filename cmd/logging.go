@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Log formats accepted by --log-format
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logFormats lists the supported log formats
+var logFormats = []string{logFormatText, logFormatJSON}
+
+// newLogHandler creates the slog handler for a log format, writing the
+// records at or above level to w
+func newLogHandler(w io.Writer, format string, level slog.Leveler) slog.Handler {
+	if format == logFormatJSON {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level:       level,
+			ReplaceAttr: renameLogAttr,
+		})
+	}
+	return &textHandler{w: w, mu: &sync.Mutex{}, level: level}
+}
+
+// renameLogAttr names the built-in JSON log fields timestamp and message
+func renameLogAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+	switch attr.Key {
+	case slog.TimeKey:
+		attr.Key = "timestamp"
+	case slog.MessageKey:
+		attr.Key = "message"
+	}
+	return attr
+}
+
+// textHandler writes plain-text log lines in the format of the standard log
+// package, followed by the record's attributes as key=value pairs, e.g.
+// "2025/01/02 15:04:05 root.go:42: message repo=org/repo". Warnings are
+// prefixed with "Warning:".
+type textHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Leveler
+	// attrs are the formatted attributes added with WithAttrs, and group the
+	// prefix of the keys of later attributes
+	attrs string
+	group string
+}
+
+// Enabled reports whether records at level are logged
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes the record as a single line
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	line := record.Time.Format("2006/01/02 15:04:05")
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		line += fmt.Sprintf(" %s:%d:", filepath.Base(frame.File), frame.Line)
+	}
+	if record.Level >= slog.LevelWarn && record.Level < slog.LevelError {
+		line += " Warning:"
+	}
+	line += " " + record.Message + h.attrs
+	record.Attrs(func(attr slog.Attr) bool {
+		line += formatLogAttr(h.group, attr)
+		return true
+	})
+	line += "\n"
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line)
+	return err
+}
+
+// WithAttrs returns a handler that writes attrs on every line
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	for _, attr := range attrs {
+		handler.attrs += formatLogAttr(h.group, attr)
+	}
+	return &handler
+}
+
+// WithGroup returns a handler that prefixes the keys of later attributes
+// with name
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.group += name + "."
+	return &handler
+}
+
+// formatLogAttr formats attr as " key=value", with the key prefixed by group.
+// Values with spaces, quotes or equals signs are quoted, and the attributes
+// of a group are written one by one.
+func formatLogAttr(group string, attr slog.Attr) string {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return ""
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			group += attr.Key + "."
+		}
+		var formatted string
+		for _, member := range attr.Value.Group() {
+			formatted += formatLogAttr(group, member)
+		}
+		return formatted
+	}
+
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		value = strconv.Quote(value)
+	}
+	return " " + group + attr.Key + "=" + value
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// clientIDEnvVar is the environment variable holding the client ID of the
+// OAuth app used by --login when --client-id is not set
+const clientIDEnvVar = "GITHUB_OAUTH_CLIENT_ID"
+
+// loginTokenFile is the cache file the token in use was read from or written
+// to by --login, so it can be removed if GitHub rejects the token
+var loginTokenFile string
+
+// loginTokenPath returns the file the --login token for the configured host is
+// cached in, under the user config directory
+func loginTokenPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user config directory: %w", err)
+	}
+
+	host := "github.com"
+	if baseURL != "" {
+		if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+			host = u.Host
+		}
+	}
+	// Ports are kept apart from the host name in a way every OS allows
+	return filepath.Join(dir, configName, "tokens", strings.ReplaceAll(host, ":", "_")), nil
+}
+
+// loginToken sets token to the one cached by a previous --login, or logs in
+// with the OAuth device flow and caches the new token. It returns a
+// description of where the token came from
+func loginToken() (string, error) {
+	path, err := loginTokenPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		token = strings.TrimSpace(string(data))
+		loginTokenFile = path
+		return "cached login " + path, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read cached login token: %w", err)
+	}
+
+	id := clientID
+	if id == "" {
+		id = os.Getenv(clientIDEnvVar)
+	}
+	if id == "" {
+		return "", fmt.Errorf("--client-id or $%s is required to log in with --login", clientIDEnvVar)
+	}
+
+	// Ctrl+C stops waiting for the login
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts := codeql.Options{BaseURL: baseURL, Proxy: proxy, CACertFile: caCertFile}
+	value, err := codeql.Login(ctx, id, logger, opts, func(code codeql.DeviceCode) {
+		fmt.Fprintf(os.Stderr, "First copy your one-time code: %s\n", code.UserCode)
+		fmt.Fprintf(os.Stderr, "Then open %s in your browser to authorize the login\n", code.VerificationURI)
+		fmt.Fprintln(os.Stderr, "Waiting for authorization...")
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to log in: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Logged in")
+
+	// Only the current user can read the cached token
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to cache login token: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(value+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to cache login token: %w", err)
+	}
+
+	token = value
+	loginTokenFile = path
+	return "--login", nil
+}
+
+// forgetLoginToken removes the cached --login token after GitHub rejected it,
+// so the next --login starts a new login
+func forgetLoginToken() {
+	if loginTokenFile == "" {
+		return
+	}
+	if err := os.Remove(loginTokenFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Error("Failed to remove cached login token", slog.String("file", loginTokenFile), slog.Any("error", err))
+		return
+	}
+	logger.Info("Removed the rejected cached login token, run with --login again to log in", slog.String("file", loginTokenFile))
+}
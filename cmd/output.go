@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/report"
+)
+
+// defaultOutputName is the output file name, without extension, used when --output is not set.
+// A split report is written to a directory of this name
+const defaultOutputName = "codeql-report"
+
+// resolveOutputFile names the default output file after the format, and warns
+// when an explicit output file has an extension that does not match the format.
+// With --split-by, --output is a directory and is used as given
+func resolveOutputFile(explicit bool) {
+	// A split report is written to a directory
+	if splitBy != "" {
+		if !explicit {
+			outputFile = defaultOutputName
+		}
+		return
+	}
+
+	extensions := report.FormatExtensions(format)
+	if !explicit {
+		outputFile = defaultOutputName + extensions[0]
+		return
+	}
+
+	if writingToStdout() {
+		return
+	}
+
+	// A compressed CSV keeps its extension before .gz
+	name := strings.ToLower(outputFile)
+	if format == report.FormatCSV {
+		name = strings.TrimSuffix(name, csvpkg.GzipExtension)
+	}
+	ext := filepath.Ext(name)
+	if !slices.Contains(extensions, ext) {
+		logger.Warn("output file does not have the extension expected for its format", slog.String("file", outputFile),
+			slog.String("extensions", strings.Join(extensions, " or ")), slog.String("format", format))
+	}
+}
+
+// stdoutOutput is the --output value that writes the report to stdout
+const stdoutOutput = report.StdoutPath
+
+// writingToStdout reports whether the report is written to stdout
+func writingToStdout() bool {
+	return outputFile == stdoutOutput
+}
+
+// messageOutput returns where status messages are printed. They go to stderr
+// when the report is written to stdout, so they do not corrupt it.
+func messageOutput() io.Writer {
+	if writingToStdout() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// reportLocation describes where the report is written, for messages
+func reportLocation() string {
+	if writingToStdout() {
+		return "stdout"
+	}
+	return outputFile
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressBarWidth is the number of characters in the bar itself
+const progressBarWidth = 30
+
+// progressBar renders a single-line progress bar on an interactive terminal
+type progressBar struct {
+	out   io.Writer
+	total int
+	start time.Time
+}
+
+// newProgressBar creates a progress bar for total items
+func newProgressBar(out io.Writer, total int) *progressBar {
+	return &progressBar{
+		out:   out,
+		total: total,
+		start: time.Now(),
+	}
+}
+
+// update redraws the bar with current items completed
+func (p *progressBar) update(current int) {
+	if p.total <= 0 {
+		return
+	}
+
+	filled := progressBarWidth * current / p.total
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+
+	eta := "--"
+	if current > 0 {
+		elapsed := time.Since(p.start)
+		remaining := elapsed / time.Duration(current) * time.Duration(p.total-current)
+		eta = remaining.Round(time.Second).String()
+	}
+
+	fmt.Fprintf(p.out, "\r[%s] %d/%d %3d%% ETA %s\033[K", bar, current, p.total, 100*current/p.total, eta)
+}
+
+// finish draws the completed bar and moves to the next line
+func (p *progressBar) finish() {
+	p.update(p.total)
+	fmt.Fprintln(p.out)
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// showProgressBar reports whether a progress bar should be drawn. It is only
+// used when stdout is a terminal and log lines are not written to the same
+// terminal, since they would break up the bar, and never with --quiet or when
+// the report itself is written to stdout.
+func showProgressBar() bool {
+	return !quiet && !writingToStdout() && isTerminal(os.Stdout) && (logFile != "" || !isTerminal(os.Stderr))
+}
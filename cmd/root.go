@@ -2,29 +2,144 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strconv"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
 	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/report"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Global flags
-	token      string
-	inputFile  string
-	outputFile string
-	logFile    string
-	verbose    bool
-
-	// Logger for the application
-	logger *log.Logger
+	token           string
+	inputFiles      []string
+	outputFile      string
+	logFile         string
+	logFormat       string
+	verbose         bool
+	quiet           bool
+	trace           bool
+	repository      string
+	organization    string
+	reposFile       string
+	state           string
+	rules           []string
+	gitRef          string
+	maxPerRepo      int
+	recordLimit     int
+	failOnEmpty     bool
+	format          string
+	maxRetries      int
+	maxTotalRetries int
+	maxAPICalls     int
+	waitForRate     bool
+	retryDelay      time.Duration
+	baseURL         string
+	proxy           string
+	caCertFile      string
+	dryRun          bool
+	cacheDir        string
+	cacheTTL        time.Duration
+	timeout         time.Duration
+	requestTimeout  time.Duration
+
+	// GitHub App authentication, used instead of the token when set
+	appID          int64
+	installationID int64
+	privateKeyFile string
+	app            *codeql.AppAuth
+
+	// OAuth device flow login, used instead of the token when set
+	login    bool
+	clientID string
+
+	// Bounds on the number of concurrent API requests
+	minWorkers     int
+	maxWorkers     int
+	workersPerHost int
+
+	// Input column names
+	repoColumn  string
+	alertColumn string
+	urlColumn   string
+	urlList     bool
+
+	// CSV parsing options
+	delimiter      string
+	lazyQuotes     bool
+	skipBadRows    bool
+	dedupe         bool
+	groupByRepo    bool
+	withSource     bool
+	withSnippet    bool
+	withHash       bool
+	withRuleFields bool
+	instanceCount  bool
+	escapeFormulas bool
+	columnNames    []string
+	headerMap      map[string]string
+
+	// Write one row per alert instance instead of only the most recent
+	allInstances bool
+
+	// Instance whose location is reported for each alert
+	instance string
+
+	// Splits the report into one file per repository
+	splitBy string
+
+	// Leaves the header row out of the CSV report
+	noHeader bool
+
+	// Title of the report, written with its metadata by Markdown, HTML and JSON
+	title string
+
+	// Keys the report is sorted by, in order
+	sortKeys []string
+
+	// Path of the checkpoint file used to resume runs
+	checkpointFile string
+
+	// Add to an existing CSV report instead of overwriting it
+	appendOutput bool
+
+	// Path of the file listing records that could not be processed
+	errorOutput string
+
+	// Path of the alerts-by-rule rollup report
+	rollupFile string
+
+	// Alert filters
+	minSeverity     string
+	includeUnranked bool
+	toolName        string
+	pathFilters     []string
+	since           string
+
+	// Creation time parsed from --since
+	sinceTime time.Time
+
+	// Minimum severity that makes the run fail
+	failOnSeverity string
+
+	// Labels replacing severity levels in the report
+	severityMap map[string]string
+
+	// Logger for the application, and the level it logs at, lowered to debug
+	// with --verbose
+	logger   *slog.Logger
+	logLevel slog.LevelVar
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -34,56 +149,193 @@ var RootCmd = &cobra.Command{
 	Long: `Generate a comprehensive CodeQL report from GitHub alerts.
 This tool takes a CSV file with repository and alert information, queries
 the GitHub API for detailed information, and outputs a formatted report.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd.Flags()); err != nil {
+			return withExitCode(exitBadInput, err)
+		}
 		setupLogging()
+		if configFile != "" {
+			logger.Info("Using config file", slog.String("file", configFile))
+		}
+		return nil
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	// Errors are printed by Execute, which also picks the exit code
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Stop fetching on Ctrl+C or termination, but still write the alerts
+		// collected so far. A second signal kills the process as usual.
+		interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-interrupted.Done()
+			stop()
+		}()
+
+		// A timeout of zero means the run is never cut short
+		ctx := interrupted
+		cancel := func() {}
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
 		defer cancel()
 
 		if err := validateFlags(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return withExitCode(exitBadInput, err)
+		}
+		resolveOutputFile(cmd.Flags().Changed("output"))
+
+		// Only validate the input when doing a dry run
+		if dryRun {
+			if err := validateInput(newConfig()); err != nil {
+				logger.Error("Dry run failed", slog.Any("error", err))
+				return withExitCode(exitBadInput, err)
+			}
+			return nil
 		}
 
 		// Process alerts and generate report
-		if err := generateReport(ctx); err != nil {
-			logger.Printf("Error generating report: %v", err)
-			fmt.Fprintf(os.Stderr, "Error generating report: %v\n", err)
-			os.Exit(1)
+		result, err := generateReport(ctx)
+		if interrupted.Err() != nil {
+			if result == nil {
+				return withExitCode(exitInterrupted, fmt.Errorf("interrupted"))
+			}
+			logger.Info("Interrupted", slog.Int("written", result.Written), slog.Int("total", result.Total))
+			return withExitCode(exitInterrupted, fmt.Errorf("interrupted, wrote %d of %d alerts", result.Written, result.Total))
+		}
+
+		code := runExitCode(result, err)
+		if err != nil {
+			logger.Error("Error generating report", slog.Any("error", err))
+			return withExitCode(code, fmt.Errorf("failed to generate report: %w", err))
+		}
+
+		// Signal degraded runs so CI can tell them apart from clean ones
+		if code != exitSuccess {
+			logger.Error("Report generated, but some alerts could not be processed", slog.String("report", reportLocation()), slog.Int("failed", result.Failed))
+			return withExitCode(code, fmt.Errorf("%d alerts could not be processed", result.Failed))
+		}
+
+		// Fail CI builds whose report contains alerts at or above the gate
+		if failOnSeverity != "" {
+			if n := result.CountAtLeast(failOnSeverity); n > 0 {
+				logger.Info("Report generated, but some alerts are at or above the severity gate", slog.String("report", reportLocation()),
+					slog.Int("alerts", n), slog.String("severity", failOnSeverity))
+				return withExitCode(exitSeverityGate, fmt.Errorf("%d alerts at or above %s severity", n, failOnSeverity))
+			}
 		}
 
-		logger.Printf("Report successfully generated at %s", outputFile)
+		logger.Info("Report successfully generated", slog.String("report", reportLocation()))
 		if verbose {
-			fmt.Printf("Report successfully generated at %s\n", outputFile)
+			fmt.Fprintf(messageOutput(), "Report successfully generated at %s\n", reportLocation())
 		}
+		return nil
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// The process exits with one of the codes defined in exit.go.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
+	// Invalid flags are bad input
+	RootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitBadInput, err)
+	})
+
 	// Define flags and their default values
-	RootCmd.PersistentFlags().StringVar(&token, "token", "", "GitHub access token (required)")
-	RootCmd.PersistentFlags().StringVar(&inputFile, "input", "", "Path to the input CSV file (required)")
-	RootCmd.PersistentFlags().StringVar(&outputFile, "output", "codeql-report.csv", "Path to the output CSV file")
+	RootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Path to a YAML or TOML config file providing flag defaults (default: <user config dir>/gh-generate-codeql-report/config.yaml)")
+	RootCmd.PersistentFlags().StringVar(&token, "token", "", "GitHub access token (default: $GITHUB_TOKEN, $GH_TOKEN, or gh CLI credentials)")
+	RootCmd.PersistentFlags().Int64Var(&appID, "app-id", 0, "Authenticate as this GitHub App instead of with a token (requires --installation-id)")
+	RootCmd.PersistentFlags().Int64Var(&installationID, "installation-id", 0, "Installation of the GitHub App to authenticate as")
+	RootCmd.PersistentFlags().StringVar(&privateKeyFile, "private-key", "", "Path to the GitHub App's private key PEM file (default: $GITHUB_APP_PRIVATE_KEY)")
+	RootCmd.PersistentFlags().BoolVar(&login, "login", false, "Log in with the OAuth device flow unless --token is set, reusing the token cached by a previous login")
+	RootCmd.PersistentFlags().StringVar(&clientID, "client-id", "", "Client ID of the OAuth app to log in with --login (default: $GITHUB_OAUTH_CLIENT_ID)")
+	RootCmd.PersistentFlags().StringSliceVar(&inputFiles, "input", nil, "Path to an input CSV file (\"-\" for stdin), repeatable or comma-separated (required unless --repo, --org or --repos-file is set)")
+	RootCmd.PersistentFlags().StringVar(&outputFile, "output", defaultOutputName+".csv", "Path to the output file (\"-\" for stdout), named after --format by default")
+	RootCmd.PersistentFlags().StringVar(&format, "format", report.FormatCSV, "Output format ("+strings.Join(report.Formats(), ", ")+")")
 	RootCmd.PersistentFlags().StringVar(&logFile, "log", "", "Path to the log file (default: stderr)")
+	RootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logFormatText, "Log format (text, json)")
 	RootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	RootCmd.PersistentFlags().BoolVar(&trace, "trace", false, "Log every GitHub API request and response, with the token redacted")
+	RootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Suppress all output except errors")
+	RootCmd.PersistentFlags().StringVar(&repository, "repo", "", "Report all alerts for a repository (owner/name) instead of reading --input")
+	RootCmd.PersistentFlags().StringVar(&reposFile, "repos-file", "", "Report all alerts for each owner/name repository listed in this file, one per line, instead of reading --input")
+	RootCmd.PersistentFlags().StringVar(&organization, "org", "", "Report all alerts for every repository in an organization instead of reading --input")
+	RootCmd.PersistentFlags().StringVar(&gitRef, "ref", "", "Report alerts as found on this git reference, e.g. refs/heads/main (default: the default branch)")
+	RootCmd.PersistentFlags().StringVar(&state, "state", "open", "Alert state to list with --repo, --org or --repos-file (open, closed, dismissed, fixed)")
+	RootCmd.PersistentFlags().StringSliceVar(&rules, "rule", nil, "Only list alerts of this rule ID with --repo, --org or --repos-file, e.g. js/sql-injection (repeatable)")
+	RootCmd.PersistentFlags().IntVar(&recordLimit, "limit", 0, "Only process the first N input records (0 means no limit)")
+	RootCmd.PersistentFlags().BoolVar(&failOnEmpty, "fail-on-empty", false, "Fail when the input contains no data rows instead of writing an empty report")
+	RootCmd.PersistentFlags().IntVar(&maxPerRepo, "max-per-repo", 0, "Report at most this many alerts per repository with --repo, --org or --repos-file (0 means no limit)")
+	RootCmd.PersistentFlags().StringVar(&repoColumn, "repo-column", "Repository", "Input CSV column containing the owner/name repository")
+	RootCmd.PersistentFlags().StringVar(&alertColumn, "alert-column", "Alert Number", "Input CSV column containing the alert number")
+	RootCmd.PersistentFlags().StringVar(&urlColumn, "url-column", "", "Input CSV column containing alert URLs, used instead of --repo-column and --alert-column")
+	RootCmd.PersistentFlags().BoolVar(&urlList, "url-list", false, "Read the input as alert URLs, one per line, instead of CSV (reads stdin unless --input is set)")
+	RootCmd.PersistentFlags().StringVar(&delimiter, "delimiter", ",", "Field delimiter for the input and output CSV (use \"tab\" for tabs)")
+	RootCmd.PersistentFlags().BoolVar(&lazyQuotes, "lazy-quotes", false, "Tolerate malformed quotes in the input CSV")
+	RootCmd.PersistentFlags().BoolVar(&skipBadRows, "skip-bad-rows", false, "Log and skip input rows whose column count does not match the header instead of failing")
+	RootCmd.PersistentFlags().BoolVar(&withSnippet, "include-snippet", false, "Add a \"Snippet\" column with the code at each alert's location (one more API call per alert)")
+	RootCmd.PersistentFlags().BoolVar(&escapeFormulas, "escape-formulas", false, "Prefix CSV values starting with =, +, -, @, tab or carriage return with ' so spreadsheets do not run them as formulas")
+	RootCmd.PersistentFlags().BoolVar(&withHash, "with-hash", false, "Add a \"Row Hash\" column with a stable hash of each alert's identity and location, for comparing reports")
+	RootCmd.PersistentFlags().BoolVar(&withRuleFields, "fields-from-rule", false, "Add \"Rule Severity\" (error, warning or note) and \"Rule Help\" columns with more of each alert's rule metadata")
+	RootCmd.PersistentFlags().BoolVar(&instanceCount, "instance-count", false, "Add an \"Instances\" column with the number of branches or refs each alert appears on (one more API call per alert)")
+	RootCmd.PersistentFlags().BoolVar(&withSource, "source-column", false, "Add a \"Source File\" column naming the input file each alert came from")
+	RootCmd.PersistentFlags().BoolVar(&dedupe, "dedupe", true, "Fetch each repository and alert number only once, even if listed more than once")
+	RootCmd.PersistentFlags().BoolVar(&groupByRepo, "group-by-repo", false, "Process the input one repository at a time, sorted by name, logging a header for each")
+	RootCmd.PersistentFlags().StringVar(&minSeverity, "min-severity", "", "Only include alerts at or above this severity (low, medium, high, critical)")
+	RootCmd.PersistentFlags().BoolVar(&includeUnranked, "include-unranked", false, "Include alerts without a security severity when --min-severity is set")
+	RootCmd.PersistentFlags().StringVar(&toolName, "tool", "CodeQL", "Only include alerts from this analysis tool (empty for all tools)")
+	RootCmd.PersistentFlags().StringSliceVar(&pathFilters, "path-filter", nil, "Only include alerts whose file path matches one of these globs, e.g. \"services/api/**\" (repeatable)")
+	RootCmd.PersistentFlags().BoolVar(&allInstances, "all-instances", false, "Write one row per branch or ref an alert appears on instead of only its most recent instance")
+	RootCmd.PersistentFlags().StringVar(&instance, "instance", report.InstanceMostRecent, "Instance whose location is reported for each alert: most-recent, or first (takes another request per alert)")
+	RootCmd.PersistentFlags().StringVar(&failOnSeverity, "fail-on-severity", "", "Exit with code 5 if the report contains alerts at or above this severity (low, medium, high, critical)")
+	RootCmd.PersistentFlags().StringVar(&since, "since", "", "Only include alerts created since this time: an RFC 3339 timestamp, a date (YYYY-MM-DD) or a duration such as 7d")
+	RootCmd.PersistentFlags().StringToStringVar(&severityMap, "severity-map", nil, "Rename severity levels in the report, e.g. \"critical=P1,high=P2\" (unmapped levels are unchanged)")
+	RootCmd.PersistentFlags().StringSliceVar(&columnNames, "columns", nil, "Comma-separated Alert fields to include in the CSV report, in order, e.g. \"Owner,Repo,ID,Severity\" (default: all)")
+	RootCmd.PersistentFlags().StringToStringVar(&headerMap, "header-map", nil, "Rename CSV report headers, keyed by Alert field or header, e.g. \"Repo=Repository,Owner=Organization\"")
+	RootCmd.PersistentFlags().BoolVar(&noHeader, "no-header", false, "Leave the header row out of the CSV report")
+	RootCmd.PersistentFlags().StringVar(&title, "title", "", "Title of the report, written with the generation time, tool version, command and alert count at the top of Markdown, HTML and JSON reports")
+	RootCmd.PersistentFlags().StringSliceVar(&sortKeys, "sort", nil, "Sort the report by these keys, e.g. \"repo,severity\" (org, repo, severity, id; default: input order)")
+	RootCmd.PersistentFlags().StringVar(&splitBy, "split-by", "", "Write one report per repository (\"repo\") into the --output directory instead of a single file")
+	RootCmd.PersistentFlags().BoolVar(&appendOutput, "append", false, "Add alerts to the end of an existing CSV or JSON Lines report instead of overwriting it")
+	RootCmd.PersistentFlags().StringVar(&errorOutput, "error-output", "", "Write the records that could not be processed, and why, to this CSV or JSON file")
+	RootCmd.PersistentFlags().StringVar(&rollupFile, "rollup", "", "Also write the number of alerts and repositories for each rule to this CSV or JSON file")
+	RootCmd.PersistentFlags().StringVar(&checkpointFile, "checkpoint", "", "File recording fetched alerts so an interrupted run can be resumed")
+	RootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Validate the input CSV without calling the API or writing output")
+	RootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "GitHub Enterprise Server URL (default: github.com)")
+	RootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "HTTP proxy URL (default: $HTTPS_PROXY)")
+	RootCmd.PersistentFlags().StringVar(&caCertFile, "ca-cert", "", "PEM file of CA certificates to trust in addition to the system certificates")
+	RootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Directory to cache fetched alerts in (default: no caching)")
+	RootCmd.PersistentFlags().DurationVar(&cacheTTL, "cache-ttl", 24*time.Hour, "How long cached alerts remain valid (0 means forever)")
+	RootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Minute, "Maximum duration of the run (0 means no timeout)")
+	RootCmd.PersistentFlags().DurationVar(&requestTimeout, "request-timeout", 0, "Maximum duration of a single API request before it is retried (0 means no timeout)")
+	RootCmd.PersistentFlags().IntVar(&minWorkers, "min-workers", 1, "Minimum number of concurrent API requests when the rate limit runs low")
+	RootCmd.PersistentFlags().IntVar(&maxWorkers, "max-workers", 4, "Maximum number of concurrent API requests")
+	RootCmd.PersistentFlags().IntVar(&workersPerHost, "workers-per-host", 0, "Maximum number of concurrent API requests to the configured API host (0 means no limit beyond --max-workers)")
+	RootCmd.PersistentFlags().IntVar(&maxRetries, "max-retries", codeql.DefaultMaxRetries, "Maximum retries for transient API errors")
+	RootCmd.PersistentFlags().IntVar(&maxTotalRetries, "max-total-retries", 0, "Maximum retries for transient API errors across the whole run (0 means no limit)")
+	RootCmd.PersistentFlags().IntVar(&maxAPICalls, "max-api-calls", 0, "Maximum number of API requests the run may make, counting retries (0 means no limit)")
+	RootCmd.PersistentFlags().BoolVar(&waitForRate, "wait-for-rate-limit", false, "Wait for the rate limit to reset before starting if it is nearly used up, instead of only warning")
+	RootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-delay", codeql.DefaultRetryDelay, "Base delay between retries, doubled on each attempt")
 }
 
 // setupLogging configures the application logger
 func setupLogging() {
-	var logWriter *os.File
+	var logWriter io.Writer
 	var err error
 
 	if logFile == "" {
 		logWriter = os.Stderr
+		// Quiet runs only print errors, which are reported by Execute
+		if quiet {
+			logWriter = io.Discard
+		}
 	} else {
 		// Create log directory if it doesn't exist
 		logDir := filepath.Dir(logFile)
@@ -99,8 +351,13 @@ func setupLogging() {
 		}
 	}
 
-	logger = log.New(logWriter, "", log.LstdFlags|log.Lshortfile)
-	logger.Println("Starting gh-generate-codeql-report")
+	// Retries and traced requests are logged at debug level, which only
+	// verbose and traced runs show
+	if verbose || trace {
+		logLevel.Set(slog.LevelDebug)
+	}
+	logger = slog.New(newLogHandler(logWriter, logFormat, &logLevel))
+	logger.Info("Starting gh-generate-codeql-report")
 }
 
 // validateFlags checks if required flags are provided
@@ -108,12 +365,31 @@ func validateFlags() error {
 	missing := false
 	var missingFlags []string
 
-	if token == "" {
-		missing = true
-		missingFlags = append(missingFlags, "token")
+	if quiet && verbose {
+		return fmt.Errorf("--quiet and --verbose cannot be used together")
+	}
+
+	// The token is not needed when no API calls are made
+	if !dryRun {
+		found, err := resolveCredentials()
+		if err != nil {
+			return err
+		}
+		if !found {
+			missing = true
+			missingFlags = append(missingFlags, "token")
+		}
+	}
+
+	if urlList && listMode() {
+		return fmt.Errorf("--url-list cannot be used with --repo, --org or --repos-file")
 	}
+	if urlList && urlColumn != "" {
+		return fmt.Errorf("--url-list cannot be used with --url-column")
+	}
+	defaultURLListInput()
 
-	if inputFile == "" {
+	if len(inputFiles) == 0 && !listMode() {
 		missing = true
 		missingFlags = append(missingFlags, "input")
 	}
@@ -122,102 +398,347 @@ func validateFlags() error {
 		return fmt.Errorf("required flag(s) not provided: %s", strings.Join(missingFlags, ", "))
 	}
 
-	return nil
-}
+	if !slices.Contains(logFormats, logFormat) {
+		return fmt.Errorf("invalid log format %q: must be one of %s", logFormat, strings.Join(logFormats, ", "))
+	}
 
-// generateReport processes the input CSV and generates the CodeQL report
-func generateReport(ctx context.Context) error {
-	logger.Printf("Reading input from %s", inputFile)
+	if !slices.Contains(report.Formats(), format) {
+		return fmt.Errorf("invalid format %q: must be one of %s", format, strings.Join(report.Formats(), ", "))
+	}
 
-	// Read input CSV
-	csvReader := csvpkg.NewReader(inputFile)
-	records, err := csvReader.ReadAllWithHeaders()
-	if err != nil {
-		return fmt.Errorf("failed to read input CSV: %w", err)
+	if minSeverity != "" && codeql.SeverityRank(minSeverity) == 0 {
+		return fmt.Errorf("invalid minimum severity %q: must be one of low, medium, high, critical", minSeverity)
 	}
 
-	logger.Printf("Found %d records to process", len(records))
+	if failOnSeverity != "" && codeql.SeverityRank(failOnSeverity) == 0 {
+		return fmt.Errorf("invalid --fail-on-severity %q: must be one of low, medium, high, critical", failOnSeverity)
+	}
 
-	// Initialize CodeQL client
-	client := codeql.NewClient(token, logger)
+	if since != "" {
+		var err error
+		if sinceTime, err = parseSince(since, time.Now()); err != nil {
+			return err
+		}
+	}
 
-	// Process each alert
-	var alerts []codeql.Alert
-	var processErrors int
+	if err := validateSeverityMap(severityMap); err != nil {
+		return err
+	}
 
-	// Set up output headers
-	outputHeaders := []string{
-		"Org", "Repo", "Alert ID", "Severity",
-		"Short Description", "Full Description",
-		"File Path", "Start Line", "Start Column",
-		"End Line", "End Column",
+	if _, err := parseDelimiter(delimiter); err != nil {
+		return err
 	}
 
-	// Create data for writing
-	var csvData [][]string
+	if err := validatePathFilters(); err != nil {
+		return err
+	}
 
-	for i, record := range records {
-		if verbose {
-			fmt.Printf("Processing record %d/%d\n", i+1, len(records))
-		}
+	if err := report.ValidateSortKeys(sortKeys); err != nil {
+		return err
+	}
+
+	if len(columnNames) > 0 && format != report.FormatCSV {
+		return fmt.Errorf("--columns requires --format csv")
+	}
+
+	if noHeader && format != report.FormatCSV {
+		return fmt.Errorf("--no-header requires --format csv")
+	}
+
+	if len(headerMap) > 0 && format != report.FormatCSV {
+		return fmt.Errorf("--header-map requires --format csv")
+	}
+
+	if err := report.ValidateColumns(columnNames, headerMap); err != nil {
+		return err
+	}
+
+	if timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+
+	if requestTimeout < 0 {
+		return fmt.Errorf("--request-timeout must not be negative")
+	}
+
+	if cacheTTL < 0 {
+		return fmt.Errorf("--cache-ttl must not be negative")
+	}
+
+	if maxRetries < 0 {
+		return fmt.Errorf("--max-retries must not be negative")
+	}
+
+	if maxTotalRetries < 0 {
+		return fmt.Errorf("--max-total-retries must not be negative")
+	}
+
+	if maxAPICalls < 0 {
+		return fmt.Errorf("--max-api-calls must not be negative")
+	}
+
+	if minWorkers < 1 {
+		return fmt.Errorf("--min-workers must be at least 1")
+	}
+
+	if maxWorkers < minWorkers {
+		return fmt.Errorf("--max-workers must not be less than --min-workers")
+	}
+
+	if workersPerHost < 0 {
+		return fmt.Errorf("--workers-per-host must not be negative")
+	}
 
-		// Extract repository owner and name
-		repoFullName := record["Repository"]
-		repoParts := strings.Split(repoFullName, "/")
-		if len(repoParts) != 2 {
-			logger.Printf("Invalid repository format: %s", repoFullName)
-			processErrors++
-			continue
+	if maxPerRepo < 0 {
+		return fmt.Errorf("--max-per-repo must not be negative")
+	}
+
+	if maxPerRepo > 0 && !listMode() {
+		return fmt.Errorf("--max-per-repo requires --repo, --org or --repos-file")
+	}
+
+	if len(rules) > 0 && !listMode() {
+		return fmt.Errorf("--rule requires --repo, --org or --repos-file")
+	}
+
+	if recordLimit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+
+	// Standard input can only be read once
+	stdinInputs := 0
+	for _, inputFile := range inputFiles {
+		if inputFile == csvpkg.StdinPath {
+			stdinInputs++
 		}
+	}
+	if stdinInputs > 1 {
+		return fmt.Errorf("--input - can only be given once")
+	}
 
-		owner := repoParts[0]
-		repo := repoParts[1]
+	if err := validateInputFiles(); err != nil {
+		return err
+	}
 
-		// Parse alert number
-		alertNumber := record["Alert Number"]
-		alertNumberInt, err := strconv.ParseInt(alertNumber, 10, 64)
-		if err != nil {
-			logger.Printf("Failed to parse alert number '%s': %v", alertNumber, err)
-			processErrors++
-			continue
+	if recordLimit > 0 && len(inputFiles) == 0 {
+		return fmt.Errorf("--limit requires --input")
+	}
+
+	if failOnEmpty && len(inputFiles) == 0 {
+		return fmt.Errorf("--fail-on-empty requires --input")
+	}
+
+	if groupByRepo && len(inputFiles) == 0 {
+		return fmt.Errorf("--group-by-repo requires --input")
+	}
+
+	if len(inputFiles) > 0 && repository != "" {
+		return fmt.Errorf("--input and --repo cannot be used together")
+	}
+
+	if organization != "" && (len(inputFiles) > 0 || repository != "") {
+		return fmt.Errorf("--org cannot be used with --input or --repo")
+	}
+
+	if reposFile != "" && (len(inputFiles) > 0 || repository != "" || organization != "") {
+		return fmt.Errorf("--repos-file cannot be used with --input, --repo or --org")
+	}
+
+	if gitRef != "" {
+		if organization != "" {
+			return fmt.Errorf("--ref cannot be used with --org")
 		}
+		if err := validateRef(gitRef); err != nil {
+			return err
+		}
+		logger.Info("Using ref", slog.String("ref", gitRef))
+	}
 
-		// Get alert details
-		alert, err := client.GetAlert(ctx, owner, repo, alertNumberInt)
-		if err != nil {
-			logger.Printf("Failed to get alert #%s for %s: %v", alertNumber, repoFullName, err)
-			processErrors++
-			continue
+	if !slices.Contains(report.Instances, instance) {
+		return fmt.Errorf("invalid instance %q: must be one of %s", instance, strings.Join(report.Instances, ", "))
+	}
+	if instance != report.InstanceMostRecent && (gitRef != "" || allInstances) {
+		return fmt.Errorf("--instance cannot be used with --ref or --all-instances")
+	}
+
+	if appendOutput && ((format != report.FormatCSV && format != report.FormatJSONL) || writingToStdout()) {
+		return fmt.Errorf("--append requires --format csv or jsonl and an --output file")
+	}
+
+	if rollupFile != "" && rollupFile == outputFile {
+		return fmt.Errorf("--rollup must be a different file from --output")
+	}
+
+	if errorOutput != "" && errorOutput == outputFile {
+		return fmt.Errorf("--error-output must be a different file from --output")
+	}
+
+	if splitBy != "" {
+		if splitBy != report.SplitByRepo {
+			return fmt.Errorf("invalid --split-by %q: must be %s", splitBy, report.SplitByRepo)
+		}
+		if appendOutput || checkpointFile != "" || writingToStdout() {
+			return fmt.Errorf("--split-by cannot be used with --append, --checkpoint or --output -")
 		}
+	}
 
-		alerts = append(alerts, *alert)
+	if checkpointFile != "" {
+		if len(inputFiles) == 0 {
+			return fmt.Errorf("--checkpoint requires --input")
+		}
+		// Resumed runs append to the existing report
+		if format != report.FormatCSV || writingToStdout() {
+			return fmt.Errorf("--checkpoint requires --format csv and an --output file")
+		}
+		// Sorted alerts are only written at the end, after being checkpointed
+		if len(sortKeys) > 0 {
+			return fmt.Errorf("--checkpoint cannot be used with --sort")
+		}
+	}
 
-		// Add to CSV data
-		csvData = append(csvData, []string{
-			alert.Owner,
-			alert.Repo,
-			strconv.Itoa(alert.ID),
-			alert.Severity,
-			alert.ShortDesc,
-			alert.FullDesc,
-			alert.FilePath,
-			strconv.Itoa(alert.StartLine),
-			strconv.Itoa(alert.StartColumn),
-			strconv.Itoa(alert.EndLine),
-			strconv.Itoa(alert.EndColumn),
-		})
+	if dryRun && len(inputFiles) == 0 {
+		return fmt.Errorf("--dry-run requires --input")
 	}
 
-	logger.Printf("Successfully processed %d/%d alerts", len(alerts), len(records))
-	if processErrors > 0 {
-		logger.Printf("Failed to process %d alerts", processErrors)
+	if repository != "" {
+		if _, _, err := report.SplitRepository(repository); err != nil {
+			return err
+		}
 	}
 
-	// Write output CSV
-	writer := csvpkg.NewWriter(outputFile, outputHeaders)
-	if err := writer.WriteAll(csvData); err != nil {
-		return fmt.Errorf("failed to write output CSV: %w", err)
+	if listMode() {
+		switch state {
+		case "open", "closed", "dismissed", "fixed":
+		default:
+			return fmt.Errorf("invalid state %q: must be one of open, closed, dismissed, fixed", state)
+		}
 	}
 
 	return nil
 }
+
+// validateRef loosely checks that ref is a git reference, such as a branch
+// name or refs/pull/42/merge. GitHub reports refs it does not know as not found.
+func validateRef(ref string) error {
+	if strings.HasPrefix(ref, "-") || strings.HasPrefix(ref, "/") || strings.HasSuffix(ref, "/") ||
+		strings.Contains(ref, "..") || strings.ContainsAny(ref, " ~^:?*[\\") {
+		return fmt.Errorf("invalid --ref %q: must be a git reference such as refs/heads/main", ref)
+	}
+	return nil
+}
+
+// listMode reports whether alerts are listed with --repo, --org or --repos-file rather than read from --input
+func listMode() bool {
+	return repository != "" || organization != "" || reposFile != ""
+}
+
+// newConfig builds the report configuration from the flags
+func newConfig() report.Config {
+	cfg := report.Config{
+		Token: token,
+		Client: codeql.Options{
+			App:            app,
+			BaseURL:        baseURL,
+			CacheDir:       cacheDir,
+			CacheTTL:       cacheTTL,
+			Trace:          trace,
+			Proxy:          proxy,
+			CACertFile:     caCertFile,
+			WorkersPerHost: workersPerHost,
+		},
+		MaxRetries:      maxRetries,
+		MaxTotalRetries: maxTotalRetries,
+		MaxAPICalls:     maxAPICalls,
+		RetryDelay:      retryDelay,
+		RequestTimeout:  requestTimeout,
+		MinWorkers:      minWorkers,
+		MaxWorkers:      maxWorkers,
+
+		WaitForRateLimit: waitForRate,
+
+		InputFiles:  inputFiles,
+		RepoColumn:  repoColumn,
+		AlertColumn: alertColumn,
+		URLColumn:   urlColumn,
+		URLList:     urlList,
+		CSV:         csvOptions(),
+		Limit:       recordLimit,
+		FailOnEmpty: failOnEmpty,
+		Dedupe:      dedupe,
+		GroupByRepo: groupByRepo,
+		Checkpoint:  checkpointFile,
+
+		Repository:   repository,
+		Organization: organization,
+		ReposFile:    reposFile,
+		State:        state,
+		Rules:        rules,
+		MaxPerRepo:   maxPerRepo,
+		Ref:          gitRef,
+
+		OutputFile:        outputFile,
+		Format:            format,
+		Append:            appendOutput,
+		Columns:           columnNames,
+		HeaderNames:       headerMap,
+		NoHeader:          noHeader,
+		Title:             title,
+		Version:           toolVersion(),
+		Command:           commandLine(),
+		WithSource:        withSource,
+		WithSnippet:       withSnippet,
+		WithHash:          withHash,
+		WithRuleFields:    withRuleFields,
+		WithInstanceCount: instanceCount,
+		AllInstances:      allInstances,
+		Instance:          instance,
+		SplitBy:           splitBy,
+		SortKeys:          sortKeys,
+		SeverityMap:       severityMap,
+		ErrorOutput:       errorOutput,
+		RollupFile:        rollupFile,
+
+		MinSeverity:     minSeverity,
+		IncludeUnranked: includeUnranked,
+		Tool:            toolName,
+		PathFilters:     pathFilters,
+		Since:           sinceTime,
+
+		Logger:  logger,
+		Verbose: verbose,
+	}
+	if !quiet {
+		cfg.Messages = messageOutput()
+	}
+	return cfg
+}
+
+// generateReport runs the report configured by the flags, drawing a progress
+// bar on terminals and falling back to line-based output otherwise
+func generateReport(ctx context.Context) (*report.Result, error) {
+	cfg := newConfig()
+
+	var progress *progressBar
+	cfg.Progress = func(done, total int) {
+		if showProgressBar() {
+			if progress == nil {
+				progress = newProgressBar(os.Stdout, total)
+			}
+			progress.update(done)
+		} else if verbose {
+			fmt.Fprintf(messageOutput(), "Processing record %d/%d\n", done+1, total)
+		}
+	}
+
+	result, err := report.Generate(ctx, cfg)
+	if errors.Is(err, codeql.ErrUnauthorized) {
+		forgetLoginToken()
+	}
+	if progress != nil {
+		progress.finish()
+	}
+	if result != nil {
+		printSummary(result)
+	}
+	return result, err
+}
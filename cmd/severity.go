@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// validateSeverityMap checks that every --severity-map entry renames a known
+// severity level to a non-empty label
+func validateSeverityMap(mappings map[string]string) error {
+	for level, label := range mappings {
+		if codeql.SeverityRank(level) == 0 {
+			return fmt.Errorf("invalid --severity-map level %q: must be one of low, medium, high, critical", level)
+		}
+		if label == "" {
+			return fmt.Errorf("invalid --severity-map label for %q: must not be empty", level)
+		}
+	}
+	return nil
+}
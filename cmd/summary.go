@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/report"
+)
+
+// printSummary writes a breakdown of alert counts by severity to stderr,
+// unless --quiet is set, and the log
+func printSummary(result *report.Result) {
+	// Order by severity, most severe first, then alphabetically
+	severities := make([]string, 0, len(result.Severities))
+	for severity := range result.Severities {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		ri, rj := codeql.SeverityRank(severities[i]), codeql.SeverityRank(severities[j])
+		if ri != rj {
+			return ri > rj
+		}
+		return severities[i] < severities[j]
+	})
+
+	parts := make([]string, 0, len(severities))
+	for _, severity := range severities {
+		parts = append(parts, fmt.Sprintf("%s: %d", severity, result.Severities[severity]))
+	}
+
+	summary := "no alerts"
+	if len(parts) > 0 {
+		summary = strings.Join(parts, ", ")
+	}
+
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Severity summary: %s\n", summary)
+	}
+	logger.Info("Severity summary", slog.String("summary", summary))
+
+	if result.Filtered > 0 {
+		logger.Info("Excluded alerts by filter", slog.Int("alerts", result.Filtered))
+	}
+
+	if result.BeforeSince > 0 {
+		logger.Info("Excluded alerts created before the since time", slog.Int("alerts", result.BeforeSince), slog.String("since", report.FormatTime(sinceTime)))
+	}
+
+	if len(result.ScanningDisabled) > 0 {
+		repos := slices.Sorted(maps.Keys(result.ScanningDisabled))
+		alerts := 0
+		for _, n := range result.ScanningDisabled {
+			alerts += n
+		}
+		if !quiet {
+			if alerts > 0 {
+				fmt.Fprintf(os.Stderr, "%d alerts were not fetched because code scanning is not enabled in %d repositories: %s\n",
+					alerts, len(repos), strings.Join(repos, ", "))
+			} else {
+				fmt.Fprintf(os.Stderr, "Code scanning is not enabled in %d repositories: %s\n", len(repos), strings.Join(repos, ", "))
+			}
+		}
+		logger.Info("Code scanning is not enabled in repositories", slog.Int("alerts", alerts),
+			slog.String("repos", strings.Join(repos, ", ")))
+	}
+
+	if result.Skipped > 0 {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Skipped %d malformed input rows\n", result.Skipped)
+		}
+		logger.Info("Skipped malformed input rows", slog.Int("rows", result.Skipped))
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Processed: %d, failed: %d, excluded: %d\n", result.Written+result.Failed+result.Filtered, result.Failed, result.Filtered)
+		printSlowestRepositories(result)
+	}
+}
+
+// slowestReposShown is the number of repositories listed by printSlowestRepositories
+const slowestReposShown = 5
+
+// printSlowestRepositories writes the repositories whose alerts took the
+// longest to fetch to stderr and the log
+func printSlowestRepositories(result *report.Result) {
+	slowest := result.SlowestRepositories(slowestReposShown)
+	if len(slowest) == 0 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "Slowest repositories:")
+	for _, t := range slowest {
+		total, average, slowest := t.Total.Round(time.Millisecond), (t.Total / time.Duration(t.Fetches)).Round(time.Millisecond),
+			t.Slowest.Round(time.Millisecond)
+		fmt.Fprintf(os.Stderr, "  %s: %v fetching %d alerts (average %v, slowest %v)\n", t.Repository, total, t.Fetches, average, slowest)
+		logger.Info("Fetch time", slog.String("repo", t.Repository), slog.Int("fetches", t.Fetches),
+			slog.Duration("total", total), slog.Duration("average", average), slog.Duration("slowest", slowest))
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// version is the version of the tool. Release builds set it with
+// -ldflags "-X github.com/lindluni/gh-generate-codeql-report/cmd.version=v1.2.3"
+var version string
+
+// toolVersion returns the version of the tool, from the build flags or the
+// version of the module it was installed from
+func toolVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// secretFlags are the flags whose values are left out of commandLine
+var secretFlags = []string{"--token"}
+
+// commandLine returns the command the tool was run with, as recorded in
+// report metadata, with the values of secretFlags redacted. Arguments with
+// spaces or quotes are quoted
+func commandLine() string {
+	args := []string{"gh", "generate-codeql-report"}
+	redactNext := false
+	for _, arg := range os.Args[1:] {
+		if redactNext {
+			arg = "REDACTED"
+			redactNext = false
+		}
+		for _, flag := range secretFlags {
+			if arg == flag {
+				redactNext = true
+			} else if strings.HasPrefix(arg, flag+"=") {
+				arg = flag + "=REDACTED"
+			}
+		}
+		if strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
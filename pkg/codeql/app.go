@@ -0,0 +1,28 @@
+package codeql
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+)
+
+// AppAuth identifies the GitHub App installation a client authenticates as,
+// instead of using a token.
+type AppAuth struct {
+	AppID          int64
+	InstallationID int64
+	// PrivateKey is the PEM-encoded private key of the app.
+	PrivateKey []byte
+}
+
+// newAppTransport returns a transport that authenticates requests with an
+// installation token of the app, created through base. The token is renewed
+// shortly before it expires, so long runs keep working.
+func newAppTransport(base http.RoundTripper, app AppAuth) (*ghinstallation.Transport, error) {
+	transport, err := ghinstallation.New(base, app.AppID, app.InstallationID, app.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure GitHub App authentication: %w", err)
+	}
+	return transport, nil
+}
@@ -0,0 +1,80 @@
+package codeql
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// cache stores raw alert responses on disk, keyed by API host, repository and
+// alert number, so repeated runs do not have to fetch them again.
+type cache struct {
+	dir string
+	// host is the API host the alerts are fetched from, so alerts of the same
+	// repository on another GitHub instance are cached separately.
+	host string
+	ttl  time.Duration
+}
+
+// path returns the file an alert is cached in. Owner and repository names are
+// case-insensitive on GitHub, so they are lowercased.
+func (c *cache) path(owner, repo string, alertNumber int64) string {
+	return filepath.Join(c.dir, strings.ReplaceAll(c.host, ":", "_"), strings.ToLower(owner), strings.ToLower(repo),
+		strconv.FormatInt(alertNumber, 10)+".json")
+}
+
+// get returns a cached alert. It returns false if the alert is not cached or
+// the entry is older than the cache TTL.
+func (c *cache) get(owner, repo string, alertNumber int64) (*github.Alert, bool, error) {
+	path := c.path(owner, repo, alertNumber)
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat cache entry %s: %w", path, err)
+	}
+
+	if c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+		return nil, false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry %s: %w", path, err)
+	}
+
+	alert := new(github.Alert)
+	if err := json.Unmarshal(data, alert); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cache entry %s: %w", path, err)
+	}
+
+	return alert, true, nil
+}
+
+// put stores an alert in the cache.
+func (c *cache) put(owner, repo string, alertNumber int64, alert *github.Alert) error {
+	path := c.path(owner, repo, alertNumber)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry %s: %w", path, err)
+	}
+
+	return nil
+}
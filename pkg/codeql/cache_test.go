@@ -0,0 +1,31 @@
+package codeql
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v72/github"
+)
+
+func TestCacheKeyedByHostAndRepository(t *testing.T) {
+	dir := t.TempDir()
+	dotcom := &cache{dir: dir, host: "api.github.com"}
+	ghes := &cache{dir: dir, host: "ghes.example.com:8443"}
+
+	if err := dotcom.put("Org", "Repo", 1, &github.Alert{Number: github.Ptr(1)}); err != nil {
+		t.Fatalf("put() error = %v", err)
+	}
+
+	// Owner and repository names are case-insensitive
+	alert, ok, err := dotcom.get("org", "repo", 1)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if !ok || alert.GetNumber() != 1 {
+		t.Errorf("get(org, repo, 1) = %v, %v, want alert #1", alert, ok)
+	}
+
+	// The same alert on another host is not cached
+	if _, ok, err := ghes.get("org", "repo", 1); err != nil || ok {
+		t.Errorf("get() on another host = %v, %v, want a miss", ok, err)
+	}
+}
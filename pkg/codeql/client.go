@@ -3,86 +3,684 @@ package codeql
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/google/go-github/v72/github"
 )
 
 // Alert represents processed CodeQL alert data.
 type Alert struct {
-	Owner       string
-	Repo        string
-	ID          int
-	Severity    string
-	ShortDesc   string
-	FullDesc    string
-	FilePath    string
-	StartLine   int
-	StartColumn int
-	EndLine     int
-	EndColumn   int
+	Owner       string `json:"owner"`
+	Repo        string `json:"repo"`
+	ID          int    `json:"alert_id"`
+	Severity    string `json:"severity"`
+	ShortDesc   string `json:"short_description"`
+	FullDesc    string `json:"full_description"`
+	FilePath    string `json:"file_path"`
+	StartLine   int    `json:"start_line"`
+	StartColumn int    `json:"start_column"`
+	EndLine     int    `json:"end_line"`
+	EndColumn   int    `json:"end_column"`
+
+	HTMLURL   string    `json:"html_url"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// DismissedBy, DismissedAt, DismissedReason and DismissedComment record
+	// who dismissed the alert, when and why. They are empty unless the alert
+	// is dismissed.
+	DismissedBy      string    `json:"dismissed_by"`
+	DismissedAt      time.Time `json:"dismissed_at"`
+	DismissedReason  string    `json:"dismissed_reason"`
+	DismissedComment string    `json:"dismissed_comment"`
+
+	RuleID string   `json:"rule_id"`
+	Tags   []string `json:"tags"`
+	CWEs   []string `json:"cwes"`
+	Tool   string   `json:"tool"`
+	// Category is the analysis category of the instance, which tells apart
+	// the analyses of repositories scanned with several configurations.
+	Category string `json:"category"`
+
+	// Ref and CommitSHA identify the instance the location was taken from.
+	Ref       string    `json:"ref"`
+	CommitSHA string    `json:"commit_sha"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// SourceFile is the input file that referenced the alert. It is set by
+	// callers, not by the API.
+	SourceFile string `json:"source_file,omitempty"`
+
+	// Snippet is the code at the alert's location. It is set by callers from
+	// GetSnippet, as fetching it takes another API call.
+	Snippet string `json:"snippet,omitempty"`
+
+	// Hash is the AlertHash of the alert. It is set by callers.
+	Hash string `json:"hash,omitempty"`
+
+	// RuleSeverity is the rule's own severity, one of error, warning or note,
+	// apart from its security severity. Help is the rule's help text in
+	// Markdown.
+	RuleSeverity string `json:"rule_severity,omitempty"`
+	Help         string `json:"help,omitempty"`
+
+	// InstanceCount is the number of git references the alert was found on.
+	// It is set by callers from CountAlertInstances, as counting them takes
+	// more API calls.
+	InstanceCount int `json:"instance_count,omitempty"`
+}
+
+// ListOptions specifies the optional filters used when listing alerts.
+type ListOptions struct {
+	// State filters alerts by state: open, closed, dismissed or fixed.
+	State string
+	// Ref filters alerts by git reference, e.g. refs/heads/main.
+	Ref string
+	// ToolName filters alerts by the analysis tool that produced them, e.g. CodeQL.
+	ToolName string
+	// RuleIDs, if set, keeps only alerts of these rules, e.g.
+	// js/sql-injection. The API cannot filter by rule, so alerts are filtered
+	// as the pages are read.
+	RuleIDs []string
+	// Limit stops listing once this many alerts have been read. When listing
+	// an organization it applies to each repository, and only trims the
+	// alerts returned: the next page may hold alerts of a repository not seen
+	// yet, so every page is still read. Zero means no limit.
+	Limit int
 }
 
+// matchesRule reports whether alerts of the rule are kept by opts.
+func (o ListOptions) matchesRule(ruleID string) bool {
+	return len(o.RuleIDs) == 0 || slices.Contains(o.RuleIDs, ruleID)
+}
+
+// Default retry settings used by NewClient.
+const (
+	DefaultMaxRetries = 3
+	DefaultRetryDelay = time.Second
+)
+
 // Client handles interactions with GitHub's CodeQL API.
 type Client struct {
-	ghClient *github.Client
-	logger   *log.Logger
-	lastRate *github.Rate
+	// MaxRetries is the number of times a request failing with a transient
+	// error is retried before giving up.
+	MaxRetries int
+	// RetryDelay is the base delay of the exponential backoff between retries.
+	RetryDelay time.Duration
+	// RequestTimeout limits how long a single request may take before it is
+	// abandoned and, like other transient errors, retried. Zero means no limit.
+	RequestTimeout time.Duration
+	// MaxTotalRetries is the number of retries shared by every call made by
+	// the client. Once it is used up, transient errors are returned without
+	// retrying. Zero means no limit.
+	MaxTotalRetries int
+	// MaxAPICalls is the number of requests the client may send, counting
+	// retries. Once they are used up, calls fail with ErrAPICallBudget
+	// without sending a request. Zero means no limit.
+	MaxAPICalls int
+	// Limiter, if set, is adjusted to the rate limit reported by every response.
+	Limiter *Limiter
+
+	services Services
+	logger   *slog.Logger
+	cache    *cache
+	// sleep waits between retries; tests replace it to avoid real waits.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu             sync.Mutex
+	lastRate       *github.Rate
+	instanceCounts map[string]int
+
+	totalRetries atomic.Int64
+	apiCalls     atomic.Int64
+}
+
+// ErrAPICallBudget means the client has sent MaxAPICalls requests, so no more
+// are sent.
+var ErrAPICallBudget = errors.New("API call budget used up")
+
+// Options configures how a Client connects to GitHub.
+type Options struct {
+	// BaseURL is the URL of a GitHub Enterprise Server instance, e.g.
+	// https://github.example.com. When empty, github.com is used.
+	BaseURL string
+	// CacheDir is the directory alert responses are cached in. When empty,
+	// caching is disabled.
+	CacheDir string
+	// CacheTTL is how long cached alerts remain valid. Zero means they never expire.
+	CacheTTL time.Duration
+	// Trace logs every HTTP request and response, with credentials redacted.
+	Trace bool
+	// Proxy is the URL of the HTTP proxy to use. When empty, the proxy is
+	// taken from the HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string
+	// CACertFile is a PEM file of CA certificates to trust in addition to
+	// the system certificates.
+	CACertFile string
+	// WorkersPerHost is the maximum number of concurrent requests to the
+	// API host. A Client sends every request to the host of BaseURL, so this
+	// caps concurrent requests to that host. Zero means no limit beyond that
+	// of the Limiter.
+	WorkersPerHost int
+	// App authenticates as a GitHub App installation instead of with the
+	// token passed to NewClient.
+	App *AppAuth
 }
 
-// NewClient creates a new CodeQL client with the provided token.
-func NewClient(token string, logger *log.Logger) *Client {
+// NewClient creates a new CodeQL client with the provided token, or
+// authenticated as the GitHub App installation in opts.App.
+func NewClient(token string, logger *slog.Logger, opts Options) (*Client, error) {
+	transport, err := newTransport(opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	var ghClient *github.Client
+	var appTransport *ghinstallation.Transport
+	if opts.App != nil {
+		if appTransport, err = newAppTransport(transport, *opts.App); err != nil {
+			return nil, err
+		}
+		ghClient = github.NewClient(&http.Client{Transport: appTransport})
+		logger.Info("Authenticating as a GitHub App installation", slog.Int64("app_id", opts.App.AppID), slog.Int64("installation_id", opts.App.InstallationID))
+	} else {
+		ghClient = github.NewClient(&http.Client{Transport: transport}).WithAuthToken(token)
+	}
+
+	if opts.BaseURL != "" {
+		if err := validateBaseURL(opts.BaseURL); err != nil {
+			return nil, err
+		}
+
+		ghClient, err = ghClient.WithEnterpriseURLs(opts.BaseURL, opts.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure GitHub Enterprise URL %s: %w", opts.BaseURL, err)
+		}
+		logger.Info("Using GitHub Enterprise Server", slog.String("base_url", ghClient.BaseURL.String()))
+	}
+	// Installation tokens are created through the same API
+	if appTransport != nil {
+		appTransport.BaseURL = strings.TrimSuffix(ghClient.BaseURL.String(), "/")
+	}
+
+	client := NewClientWithServices(newServices(ghClient), logger)
+	if opts.CacheDir != "" {
+		client.cache = &cache{dir: opts.CacheDir, host: ghClient.BaseURL.Host, ttl: opts.CacheTTL}
+		logger.Info("Caching alerts", slog.String("cache_dir", opts.CacheDir))
+	}
+
+	return client, nil
+}
+
+// NewClientWithServices creates a CodeQL client that calls the given
+// services instead of connecting to GitHub, e.g. fakes in tests.
+func NewClientWithServices(services Services, logger *slog.Logger) *Client {
 	return &Client{
-		ghClient: github.NewClient(nil).WithAuthToken(token),
-		logger:   logger,
+		MaxRetries: DefaultMaxRetries,
+		RetryDelay: DefaultRetryDelay,
+		services:   services,
+		logger:     logger,
+		sleep:      sleep,
+	}
+}
+
+// validateBaseURL checks that baseURL is an absolute http(s) URL.
+func validateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid base URL %q: missing host", baseURL)
 	}
+	return nil
 }
 
 // GetAlert fetches a CodeQL alert by its number.
 func (c *Client) GetAlert(ctx context.Context, owner, repo string, alertNumber int64) (*Alert, error) {
-	c.logger.Printf("Fetching alert #%d for %s/%s", alertNumber, owner, repo)
+	if c.cache != nil {
+		alert, ok, err := c.cache.get(owner, repo, alertNumber)
+		if err != nil {
+			c.logger.Info("Ignoring cache entry", append(AlertAttrs(owner, repo, alertNumber), slog.Any("error", err))...)
+		} else if ok {
+			c.logger.Info("Using cached alert", AlertAttrs(owner, repo, alertNumber)...)
+			return newAlert(owner, repo, alert), nil
+		}
+	}
+
+	c.logger.Info("Fetching alert", AlertAttrs(owner, repo, alertNumber)...)
+
+	var alert *github.Alert
+	err := c.do(ctx, func(ctx context.Context) (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		alert, resp, err = c.services.CodeScanning.GetAlert(ctx, owner, repo, alertNumber)
+		return resp, err
+	}, AlertAttrs(owner, repo, alertNumber)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert: %w", err)
+	}
+
+	if c.cache != nil {
+		if err := c.cache.put(owner, repo, alertNumber, alert); err != nil {
+			c.logger.Error("Failed to cache alert", append(AlertAttrs(owner, repo, alertNumber), slog.Any("error", err))...)
+		}
+	}
+
+	return newAlert(owner, repo, alert), nil
+}
+
+// ListAlerts fetches every CodeQL alert for a repository matching opts,
+// following pagination until all pages have been read or opts.Limit alerts
+// have been found.
+func (c *Client) ListAlerts(ctx context.Context, owner, repo string, opts ListOptions) ([]Alert, error) {
+	c.logger.Info("Listing alerts", slog.String("repo", owner+"/"+repo))
+
+	perPage := 100
+	if opts.Limit > 0 {
+		perPage = min(perPage, opts.Limit)
+	}
+
+	listOpts := &github.AlertListOptions{
+		State:       opts.State,
+		Ref:         opts.Ref,
+		ToolName:    opts.ToolName,
+		ListOptions: github.ListOptions{PerPage: perPage},
+	}
+
+	var alerts []Alert
+	for {
+		var page []*github.Alert
+		var resp *github.Response
+		err := c.do(ctx, func(ctx context.Context) (*github.Response, error) {
+			var err error
+			page, resp, err = c.services.CodeScanning.ListAlertsForRepo(ctx, owner, repo, listOpts)
+			return resp, err
+		}, "repo", owner+"/"+repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list alerts: %w", err)
+		}
+
+		for _, alert := range page {
+			if !opts.matchesRule(alert.GetRule().GetID()) {
+				continue
+			}
+			alerts = append(alerts, *newAlert(owner, repo, alert))
+		}
+
+		if opts.Limit > 0 && len(alerts) >= opts.Limit {
+			alerts = alerts[:opts.Limit]
+			c.logger.Info("Stopped listing alerts at the limit", slog.String("repo", owner+"/"+repo), slog.Int("limit", opts.Limit))
+			break
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.ListOptions.Page = resp.NextPage
+	}
+
+	c.logger.Info("Found alerts", slog.String("repo", owner+"/"+repo), slog.Int("alerts", len(alerts)))
+	return alerts, nil
+}
+
+// ListAlertsForOrg fetches every CodeQL alert matching opts across all
+// repositories of an organization, following pagination until all pages have
+// been read. Repositories without code scanning have no alerts, so they are
+// simply not included. Alerts of a repository past opts.Limit are dropped, but
+// listing goes on to the last page.
+func (c *Client) ListAlertsForOrg(ctx context.Context, org string, opts ListOptions) ([]Alert, error) {
+	c.logger.Info("Listing alerts for organization", slog.String("org", org))
+
+	listOpts := &github.AlertListOptions{
+		State:       opts.State,
+		Ref:         opts.Ref,
+		ToolName:    opts.ToolName,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var alerts []Alert
+	perRepo := make(map[string]int)
+	for {
+		var page []*github.Alert
+		var resp *github.Response
+		err := c.do(ctx, func(ctx context.Context) (*github.Response, error) {
+			var err error
+			page, resp, err = c.services.CodeScanning.ListAlertsForOrg(ctx, org, listOpts)
+			return resp, err
+		}, "org", org)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list alerts: %w", err)
+		}
+
+		for _, alert := range page {
+			if !opts.matchesRule(alert.GetRule().GetID()) {
+				continue
+			}
+
+			// The organization endpoint names the repository of each alert
+			repository := alert.GetRepository()
+			fullName := repository.GetFullName()
+			if opts.Limit > 0 && perRepo[fullName] >= opts.Limit {
+				continue
+			}
+			perRepo[fullName]++
 
+			alerts = append(alerts, *newAlert(repository.GetOwner().GetLogin(), repository.GetName(), alert))
+		}
+
+		// The endpoint paginates by page number or by cursor
+		if resp.NextPage == 0 && resp.After == "" {
+			break
+		}
+		listOpts.ListOptions.Page = resp.NextPage
+		listOpts.ListCursorOptions.After = resp.After
+	}
+
+	c.logger.Info("Found alerts for organization", slog.String("org", org), slog.Int("alerts", len(alerts)), slog.Int("repositories", len(perRepo)))
+	return alerts, nil
+}
+
+// RateStatus returns the rate limit reported by the most recent API response,
+// or nil if no request has completed yet.
+func (c *Client) RateStatus() *github.Rate {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastRate
+}
+
+// recordRate stores the rate limit of a response and passes it on to the Limiter.
+func (c *Client) recordRate(rate github.Rate) {
+	c.mu.Lock()
+	c.lastRate = &rate
+	c.mu.Unlock()
+
+	if c.Limiter != nil {
+		c.Limiter.Adjust(rate)
+	}
+}
+
+// do runs a single API call, sleeping and retrying it when the GitHub rate
+// limit has been exhausted or a transient error occurred, and records the
+// rate limit of the response. Each attempt is passed a context that ends
+// after RequestTimeout, or when ctx does. Retries are logged with attrs, which
+// identify what the call is for.
+func (c *Client) do(ctx context.Context, call func(ctx context.Context) (*github.Response, error), attrs ...any) error {
+	retries := 0
 	for {
-		alert, resp, err := c.ghClient.CodeScanning.GetAlert(ctx, owner, repo, alertNumber)
+		resp, err := c.attempt(ctx, call)
 		if err != nil {
-			// Check for rate limit error
-			if resp != nil && resp.StatusCode == http.StatusForbidden {
-				if rl := resp.Rate; rl.Remaining == 0 {
+			// Check for rate limit error. A response without rate limit
+			// headers has a zero Rate, which must not be recorded
+			if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) {
+				if rl := resp.Rate; rl.Limit > 0 && rl.Remaining == 0 {
+					c.recordRate(rl)
 					reset := rl.Reset.Time.Sub(time.Now())
 					if reset > 0 {
-						c.logger.Printf("GitHub rate limit reached. Sleeping for %v until %v", reset, rl.Reset.Time)
-						time.Sleep(reset)
+						c.logger.Info("GitHub rate limit reached, sleeping until it resets", slog.Duration("wait", reset), slog.Time("reset_at", rl.Reset.Time))
+						if err := c.sleep(ctx, reset); err != nil {
+							return err
+						}
 						continue // retry after sleep
 					}
 				}
 			}
-			return nil, fmt.Errorf("failed to get alert: %w", err)
+
+			// Wait out a secondary rate limit before retrying. Like 429
+			// responses, these retries count against the retry budget.
+			if wait, ok := secondaryRateLimitWait(resp, err); ok && ctx.Err() == nil && retries < c.MaxRetries && c.takeRetry() {
+				retries++
+				c.logger.Debug("GitHub secondary rate limit reached, retrying", retryAttrs(wait, retries, c.MaxRetries, attrs...)...)
+				if err := c.sleep(ctx, wait); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Wait as long as a 429 response asks before retrying, or back off
+			// if it does not say. These retries count against the retry budget.
+			if wait, ok := tooManyRequestsWait(resp); ok && ctx.Err() == nil && retries < c.MaxRetries && c.takeRetry() {
+				retries++
+				if wait == 0 {
+					wait = c.backoff(retries)
+				}
+				c.logger.Debug("Too many requests, retrying", retryAttrs(wait, retries, c.MaxRetries, attrs...)...)
+				if err := c.sleep(ctx, wait); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Retry transient errors with exponential backoff, waiting longer
+			// for gateway errors as GitHub is likely having a brief outage
+			if ctx.Err() == nil && retries < c.MaxRetries && isTransient(resp, err) && c.takeRetry() {
+				retries++
+				delay := c.backoff(retries)
+				message, detail := "Transient error, retrying", slog.Any("error", err)
+				if isGatewayError(resp) {
+					delay *= gatewayErrorDelayFactor
+					message, detail = "GitHub gateway error, retrying", slog.String("status", resp.Status)
+				}
+				c.logger.Debug(message, append(retryAttrs(delay, retries, c.MaxRetries, attrs...), detail)...)
+				if err := c.sleep(ctx, delay); err != nil {
+					return err
+				}
+				continue
+			}
+			return classifyError(err)
 		}
 
-		// Log and store rate limit info
-		if resp != nil {
-			c.lastRate = &resp.Rate
-			if c.lastRate.Remaining < 10 {
-				c.logger.Printf("Warning: GitHub API rate limit low: %d remaining, resets at %v", c.lastRate.Remaining, c.lastRate.Reset.Time)
+		// Log and store rate limit info, if the response reports it
+		if resp != nil && resp.Rate.Limit > 0 {
+			c.recordRate(resp.Rate)
+			if resp.Rate.Remaining < LowRateLimit {
+				c.logger.Warn("GitHub API rate limit low", RateAttrs(resp.Rate)...)
 			}
 		}
 
-		location := alert.MostRecentInstance.GetLocation()
-		return &Alert{
-			Owner:       owner,
-			Repo:        repo,
-			ID:          alert.GetNumber(),
-			Severity:    alert.Rule.GetSecuritySeverityLevel(),
-			ShortDesc:   alert.Rule.GetDescription(),
-			FullDesc:    alert.Rule.GetFullDescription(),
-			FilePath:    location.GetPath(),
-			StartLine:   location.GetStartLine(),
-			StartColumn: location.GetStartColumn(),
-			EndLine:     location.GetEndLine(),
-			EndColumn:   location.GetEndColumn(),
-		}, nil
+		return nil
+	}
+}
+
+// takeRetry reports whether the retry budget allows another retry, and if so
+// uses up one retry of it. The budget running out is logged once.
+func (c *Client) takeRetry() bool {
+	if c.MaxTotalRetries <= 0 {
+		return true
+	}
+
+	n := c.totalRetries.Add(1)
+	if n == int64(c.MaxTotalRetries)+1 {
+		c.logger.Warn("retry budget used up, failing transient errors without retrying", slog.Int("max_total_retries", c.MaxTotalRetries))
+	}
+	return n <= int64(c.MaxTotalRetries)
+}
+
+// takeAPICall reports whether the API call budget allows another request, and
+// if so uses up one call of it. The budget running out is logged once.
+func (c *Client) takeAPICall() bool {
+	n := c.apiCalls.Add(1)
+	if c.MaxAPICalls <= 0 {
+		return true
+	}
+
+	if n == int64(c.MaxAPICalls)+1 {
+		c.logger.Warn("API call budget reached, no more requests are sent", slog.Int("max_api_calls", c.MaxAPICalls))
+	}
+	return n <= int64(c.MaxAPICalls)
+}
+
+// APICalls returns the number of requests the client has sent, counting
+// retries.
+func (c *Client) APICalls() int {
+	n := int(c.apiCalls.Load())
+	if c.MaxAPICalls > 0 {
+		n = min(n, c.MaxAPICalls)
+	}
+	return n
+}
+
+// attempt runs call once, with the RequestTimeout applied to its context. It
+// fails with ErrAPICallBudget if the API call budget is used up.
+func (c *Client) attempt(ctx context.Context, call func(ctx context.Context) (*github.Response, error)) (*github.Response, error) {
+	if !c.takeAPICall() {
+		return nil, ErrAPICallBudget
+	}
+	if c.RequestTimeout <= 0 {
+		return call(ctx)
+	}
+
+	// The derived deadline can only be earlier than that of ctx
+	requestCtx, cancel := context.WithTimeout(ctx, c.RequestTimeout)
+	defer cancel()
+
+	resp, err := call(requestCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("request timed out after %v: %w", c.RequestTimeout, err)
+	}
+	return resp, err
+}
+
+// backoff returns the delay before the given retry attempt: the base delay
+// doubled for every previous attempt, plus up to the same amount of jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.RetryDelay << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+	return delay + rand.N(delay)
+}
+
+// gatewayErrorDelayFactor multiplies the backoff delay before retrying a
+// gateway error.
+const gatewayErrorDelayFactor = 2
+
+// isGatewayError reports whether a request failed with 502 Bad Gateway, 503
+// Service Unavailable or 504 Gateway Timeout, which GitHub returns during
+// brief outages.
+func isGatewayError(resp *github.Response) bool {
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
 	}
+	return false
+}
+
+// isTransient reports whether a failed request may succeed if retried.
+func isTransient(resp *github.Response, err error) bool {
+	if isGatewayError(resp) || (resp != nil && resp.StatusCode == http.StatusInternalServerError) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	// A request that ran into RequestTimeout. Callers check that the parent
+	// context is still live before retrying.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// sleep pauses for d, returning early with an error if ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// newAlert converts a go-github code scanning alert into an Alert.
+func newAlert(owner, repo string, alert *github.Alert) *Alert {
+	location := alert.MostRecentInstance.GetLocation()
+	tags := ruleTags(alert.Rule)
+	result := &Alert{
+		Owner:       owner,
+		Repo:        repo,
+		ID:          alert.GetNumber(),
+		Severity:    alert.Rule.GetSecuritySeverityLevel(),
+		ShortDesc:   alert.Rule.GetDescription(),
+		FullDesc:    alert.Rule.GetFullDescription(),
+		FilePath:    location.GetPath(),
+		StartLine:   location.GetStartLine(),
+		StartColumn: location.GetStartColumn(),
+		EndLine:     location.GetEndLine(),
+		EndColumn:   location.GetEndColumn(),
+
+		HTMLURL:   alert.GetHTMLURL(),
+		State:     alert.GetState(),
+		CreatedAt: alert.GetCreatedAt().Time,
+
+		RuleID: alert.Rule.GetID(),
+		Tags:   tags,
+		CWEs:   ruleCWEs(tags),
+		Tool:   alert.Tool.GetName(),
+
+		Category:  instanceCategory(alert.MostRecentInstance),
+		Ref:       alert.MostRecentInstance.GetRef(),
+		CommitSHA: alert.MostRecentInstance.GetCommitSHA(),
+		UpdatedAt: alert.GetUpdatedAt().Time,
+
+		RuleSeverity: alert.Rule.GetSeverity(),
+		Help:         alert.Rule.GetHelp(),
+	}
+
+	if result.State == "dismissed" {
+		result.DismissedBy = alert.DismissedBy.GetLogin()
+		result.DismissedAt = alert.GetDismissedAt().Time
+		result.DismissedReason = alert.GetDismissedReason()
+		result.DismissedComment = alert.GetDismissedComment()
+	}
+	return result
+}
+
+// ruleTags returns the tags of a rule, or an empty slice if it has none.
+func ruleTags(rule *github.Rule) []string {
+	if rule == nil || rule.Tags == nil {
+		return []string{}
+	}
+	return rule.Tags
+}
+
+// AlertAttrs returns the structured log attributes identifying an alert.
+func AlertAttrs(owner, repo string, alertNumber int64) []any {
+	return []any{slog.String("repo", owner+"/"+repo), slog.Int64("alert_number", alertNumber)}
+}
+
+// RateAttrs returns the structured log attributes describing a rate limit.
+func RateAttrs(rate github.Rate) []any {
+	return []any{slog.Int("remaining", rate.Remaining), slog.Int("limit", rate.Limit), slog.Time("reset_at", rate.Reset.Time)}
+}
+
+// retryAttrs returns the structured log attributes of a retry, the wait
+// before it and which attempt it is, followed by attrs.
+func retryAttrs(wait time.Duration, attempt, maxRetries int, attrs ...any) []any {
+	return append([]any{slog.Duration("wait", wait), slog.Int("attempt", attempt), slog.Int("max_retries", maxRetries)}, attrs...)
 }
@@ -0,0 +1,195 @@
+package codeql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// fakeResponse is a response of fakeCodeScanning. A 200 status returns the
+// requested alert; any other status fails with a *github.ErrorResponse.
+type fakeResponse struct {
+	status  int
+	header  http.Header
+	message string
+}
+
+// fakeCodeScanning is a CodeScanningAPI whose GetAlert answers with its
+// responses in turn, repeating the last one. Other methods are not
+// implemented.
+type fakeCodeScanning struct {
+	CodeScanningAPI
+	responses []fakeResponse
+	calls     int
+}
+
+func (f *fakeCodeScanning) GetAlert(_ context.Context, _, _ string, id int64) (*github.Alert, *github.Response, error) {
+	r := f.responses[min(f.calls, len(f.responses)-1)]
+	f.calls++
+
+	header := r.header
+	if header == nil {
+		header = http.Header{}
+	}
+	httpResp := &http.Response{
+		StatusCode: r.status,
+		Status:     fmt.Sprintf("%d %s", r.status, http.StatusText(r.status)),
+		Header:     header,
+	}
+	resp := &github.Response{Response: httpResp}
+	if r.status != http.StatusOK {
+		return nil, resp, &github.ErrorResponse{Response: httpResp, Message: r.message}
+	}
+	return &github.Alert{Number: github.Ptr(int(id))}, resp, nil
+}
+
+// newTestClient creates a Client calling a fakeCodeScanning with the given
+// responses. Its waits are recorded in waits instead of being slept.
+func newTestClient(responses ...fakeResponse) (*Client, *fakeCodeScanning, *[]time.Duration) {
+	fake := &fakeCodeScanning{responses: responses}
+	client := NewClientWithServices(Services{CodeScanning: fake}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client.RetryDelay = time.Millisecond
+
+	var waits []time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return client, fake, &waits
+}
+
+func TestTooManyRequestsRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		minWait    time.Duration
+		maxWait    time.Duration
+	}{
+		{name: "delta seconds", retryAfter: "7", minWait: 7 * time.Second, maxWait: 7 * time.Second},
+		{name: "HTTP date", retryAfter: time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat),
+			minWait: 28 * time.Second, maxWait: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake, waits := newTestClient(
+				fakeResponse{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {tt.retryAfter}}},
+				fakeResponse{status: http.StatusOK},
+			)
+
+			alert, err := client.GetAlert(context.Background(), "org", "repo", 42)
+			if err != nil {
+				t.Fatalf("GetAlert() error = %v", err)
+			}
+			if alert.ID != 42 {
+				t.Errorf("alert ID = %d, want 42", alert.ID)
+			}
+			if fake.calls != 2 {
+				t.Errorf("GetAlert called %d times, want 2", fake.calls)
+			}
+			if len(*waits) != 1 {
+				t.Fatalf("waited %d times, want once", len(*waits))
+			}
+			if wait := (*waits)[0]; wait < tt.minWait || wait > tt.maxWait {
+				t.Errorf("waited %v, want between %v and %v", wait, tt.minWait, tt.maxWait)
+			}
+			// The 429 reported no rate limit, so none must be recorded
+			if rate := client.RateStatus(); rate != nil {
+				t.Errorf("RateStatus() = %v, want nil", rate)
+			}
+		})
+	}
+}
+
+func TestGatewayErrorsAreRetried(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		failures    int
+		wantRetries int
+		wantErr     bool
+	}{
+		{name: "bad gateway", status: http.StatusBadGateway, failures: 3, wantRetries: 3},
+		{name: "service unavailable", status: http.StatusServiceUnavailable, failures: 3, wantRetries: 3},
+		{name: "gateway timeout", status: http.StatusGatewayTimeout, failures: 3, wantRetries: 3},
+		{name: "more failures than retries", status: http.StatusBadGateway, failures: DefaultMaxRetries + 1,
+			wantRetries: DefaultMaxRetries, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var responses []fakeResponse
+			for range tt.failures {
+				responses = append(responses, fakeResponse{status: tt.status, message: http.StatusText(tt.status)})
+			}
+			client, fake, waits := newTestClient(append(responses, fakeResponse{status: http.StatusOK})...)
+
+			alert, err := client.GetAlert(context.Background(), "org", "repo", 7)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetAlert() = %v, want an error", alert)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("GetAlert() error = %v", err)
+				}
+				if alert.ID != 7 {
+					t.Errorf("alert ID = %d, want 7", alert.ID)
+				}
+			}
+
+			if retries := fake.calls - 1; retries != tt.wantRetries {
+				t.Errorf("retried %d times, want %d", retries, tt.wantRetries)
+			}
+			if len(*waits) != tt.wantRetries {
+				t.Fatalf("waited %d times, want %d", len(*waits), tt.wantRetries)
+			}
+			// Gateway errors back off longer than other transient errors
+			for i, wait := range *waits {
+				if want := gatewayErrorDelayFactor * client.RetryDelay << i; wait < want {
+					t.Errorf("wait %d was %v, want at least %v", i+1, wait, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSecondaryRateLimitRetriesAreLimited(t *testing.T) {
+	client, fake, waits := newTestClient(fakeResponse{
+		status:  http.StatusForbidden,
+		header:  http.Header{"Retry-After": {"60"}},
+		message: "You have exceeded a secondary rate limit",
+	})
+	client.MaxRetries = 2
+
+	if alert, err := client.GetAlert(context.Background(), "org", "repo", 1); err == nil {
+		t.Fatalf("GetAlert() = %v, want an error", alert)
+	}
+	if fake.calls != 3 {
+		t.Errorf("GetAlert called %d times, want 3", fake.calls)
+	}
+	if len(*waits) != 2 {
+		t.Fatalf("waited %d times, want twice", len(*waits))
+	}
+	for i, wait := range *waits {
+		if wait != time.Minute {
+			t.Errorf("wait %d was %v, want %v", i+1, wait, time.Minute)
+		}
+	}
+
+	// The retries are taken from the retry budget too
+	client, fake, _ = newTestClient(fakeResponse{status: http.StatusForbidden, header: http.Header{"Retry-After": {"60"}}})
+	client.MaxTotalRetries = 1
+	if _, err := client.GetAlert(context.Background(), "org", "repo", 1); err == nil {
+		t.Fatal("GetAlert() error = nil with the retry budget used up, want an error")
+	}
+	if fake.calls != 2 {
+		t.Errorf("GetAlert called %d times with a budget of one retry, want 2", fake.calls)
+	}
+}
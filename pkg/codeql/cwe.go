@@ -0,0 +1,40 @@
+package codeql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cweTagPrefix is the prefix of rule tags naming a CWE, e.g. external/cwe/cwe-079.
+const cweTagPrefix = "external/cwe/cwe-"
+
+// ruleCWEs extracts the CWE identifiers from a rule's tags, normalized to the
+// CWE-79 form, in the order they appear. Tags that are not CWE references are
+// ignored, and it returns an empty slice if there are none.
+func ruleCWEs(tags []string) []string {
+	cwes := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		cwe, ok := parseCWETag(tag)
+		if !ok || seen[cwe] {
+			continue
+		}
+		seen[cwe] = true
+		cwes = append(cwes, cwe)
+	}
+	return cwes
+}
+
+// parseCWETag converts a tag such as external/cwe/cwe-079 into CWE-79.
+func parseCWETag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !strings.HasPrefix(tag, cweTagPrefix) {
+		return "", false
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(tag, cweTagPrefix))
+	if err != nil || id <= 0 {
+		return "", false
+	}
+	return "CWE-" + strconv.Itoa(id), true
+}
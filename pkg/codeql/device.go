@@ -0,0 +1,179 @@
+package codeql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultWebURL is where device flow logins are made when Options.BaseURL is
+// not set.
+const defaultWebURL = "https://github.com"
+
+// LoginScopes are the OAuth scopes requested by Login, enough to read code
+// scanning alerts in public and private repositories.
+var LoginScopes = []string{"security_events"}
+
+// Errors returned by Login when the user does not authorize it.
+var (
+	ErrLoginExpired = errors.New("the one-time code expired before the login was authorized")
+	ErrLoginDenied  = errors.New("the login was denied")
+)
+
+// DeviceCode is the one-time code the user enters at VerificationURI to
+// authorize a device flow login.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// Login obtains a token with GitHub's OAuth device flow for the OAuth app
+// clientID. It requests a one-time code, passes it to prompt to show to the
+// user, and waits until the user has authorized the login in their browser.
+// Requests are made to the web URL of opts.BaseURL, with the proxy and CA
+// certificates of opts.
+func Login(ctx context.Context, clientID string, logger *slog.Logger, opts Options, prompt func(DeviceCode)) (string, error) {
+	transport, err := newTransport(opts, logger)
+	if err != nil {
+		return "", err
+	}
+	webURL, err := deviceWebURL(opts.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	flow := &deviceFlow{client: &http.Client{Transport: transport}, webURL: webURL, clientID: clientID}
+
+	code, err := flow.requestCode(ctx)
+	if err != nil {
+		return "", err
+	}
+	prompt(*code)
+	return flow.pollToken(ctx, *code)
+}
+
+// deviceWebURL returns the web URL logins are made at for baseURL, which may
+// be given with or without the /api/v3 path of the API.
+func deviceWebURL(baseURL string) (string, error) {
+	if baseURL == "" {
+		return defaultWebURL, nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q", baseURL)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// deviceFlow makes the requests of a device flow login.
+type deviceFlow struct {
+	client   *http.Client
+	webURL   string
+	clientID string
+}
+
+// tokenResponse is the response to a poll for the token. Error is set until
+// the login is authorized.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	Interval         int    `json:"interval"`
+}
+
+// requestCode requests the one-time code the user authorizes the login with.
+func (f *deviceFlow) requestCode(ctx context.Context) (*DeviceCode, error) {
+	form := url.Values{"client_id": {f.clientID}, "scope": {strings.Join(LoginScopes, " ")}}
+
+	var code DeviceCode
+	if err := f.post(ctx, "/login/device/code", form, &code); err != nil {
+		return nil, fmt.Errorf("failed to request a login code: %w", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, fmt.Errorf("failed to request a login code: no code in the response, check the OAuth app's client ID and that device flow is enabled for it")
+	}
+	return &code, nil
+}
+
+// pollToken polls for the token at the interval GitHub asks for, until the
+// user has authorized or denied the login, or the code has expired.
+func (f *deviceFlow) pollToken(ctx context.Context, code DeviceCode) (string, error) {
+	form := url.Values{
+		"client_id":   {f.clientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+
+	interval := time.Duration(max(code.Interval, 1)) * time.Second
+	expires := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+
+		var resp tokenResponse
+		if err := f.post(ctx, "/login/oauth/access_token", form, &resp); err != nil {
+			return "", fmt.Errorf("failed to get the login token: %w", err)
+		}
+
+		switch resp.Error {
+		case "":
+			if resp.AccessToken == "" {
+				return "", fmt.Errorf("failed to get the login token: no token in the response")
+			}
+			return resp.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			// Polling too often adds to the interval from now on
+			if resp.Interval > 0 {
+				interval = time.Duration(resp.Interval) * time.Second
+			} else {
+				interval += 5 * time.Second
+			}
+		case "expired_token":
+			return "", ErrLoginExpired
+		case "access_denied":
+			return "", ErrLoginDenied
+		default:
+			return "", fmt.Errorf("failed to get the login token: %s: %s", resp.Error, resp.ErrorDescription)
+		}
+
+		if code.ExpiresIn > 0 && time.Now().After(expires) {
+			return "", ErrLoginExpired
+		}
+	}
+}
+
+// post sends form to path of the web URL and decodes the JSON response into v.
+func (f *deviceFlow) post(ctx context.Context, path string, form url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.webURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("POST %s: %s", req.URL, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode the response of POST %s: %w", req.URL, err)
+	}
+	return nil
+}
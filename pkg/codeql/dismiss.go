@@ -0,0 +1,46 @@
+package codeql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// DismissReasons lists the reasons GitHub accepts for dismissing an alert.
+var DismissReasons = []string{"false positive", "won't fix", "used in tests"}
+
+// DismissAlert dismisses a CodeQL alert with one of DismissReasons and an
+// optional comment, and returns the updated alert.
+func (c *Client) DismissAlert(ctx context.Context, owner, repo string, alertNumber int64, reason, comment string) (*Alert, error) {
+	c.logger.Info("Dismissing alert", append(AlertAttrs(owner, repo, alertNumber), slog.String("reason", reason))...)
+
+	update := &github.CodeScanningAlertState{
+		State:           "dismissed",
+		DismissedReason: github.Ptr(reason),
+	}
+	if comment != "" {
+		update.DismissedComment = github.Ptr(comment)
+	}
+
+	var alert *github.Alert
+	err := c.do(ctx, func(ctx context.Context) (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		alert, resp, err = c.services.CodeScanning.UpdateAlert(ctx, owner, repo, alertNumber, update)
+		return resp, err
+	}, AlertAttrs(owner, repo, alertNumber)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dismiss alert: %w", err)
+	}
+
+	// Keep cached reports from showing the alert as still open
+	if c.cache != nil {
+		if err := c.cache.put(owner, repo, alertNumber, alert); err != nil {
+			c.logger.Error("Failed to cache alert", append(AlertAttrs(owner, repo, alertNumber), slog.Any("error", err))...)
+		}
+	}
+
+	return newAlert(owner, repo, alert), nil
+}
@@ -0,0 +1,119 @@
+package codeql
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// Errors that an *APIError matches with errors.Is, depending on its status code.
+var (
+	// ErrNotFound means the repository or alert does not exist, code scanning
+	// is not enabled, or the token cannot see the repository.
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized means the token is invalid or expired.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden means the token lacks the permissions the request needs.
+	ErrForbidden = errors.New("forbidden")
+	// ErrScanningDisabled means code scanning is not enabled for the
+	// repository, or it has no analyses yet. It is matched in addition to
+	// ErrNotFound or ErrForbidden.
+	ErrScanningDisabled = errors.New("code scanning is not enabled")
+)
+
+// scanningDisabledMessages are the parts of the messages GitHub responds with
+// when a repository has no code scanning, in lower case.
+var scanningDisabledMessages = []string{
+	"code scanning is not enabled",
+	"no analysis found",
+	"advanced security must be enabled",
+}
+
+// APIError is a failed request whose status code usually points to a problem
+// the user can fix, such as a wrong alert number or missing token scope.
+type APIError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v (%s)", e.Err, e.hint())
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the status code corresponds to target, one of
+// ErrNotFound, ErrUnauthorized or ErrForbidden, or whether the response says
+// that code scanning is disabled for ErrScanningDisabled.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrScanningDisabled:
+		return scanningDisabled(e.Err)
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// hint suggests what to check for the status code.
+func (e *APIError) hint() string {
+	if scanningDisabled(e.Err) {
+		return "enable code scanning for the repository to report its alerts"
+	}
+
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return "check that the repository and alert exist, that code scanning is enabled, and that the token can access the repository"
+	case http.StatusUnauthorized:
+		return "the token is invalid or has expired"
+	default:
+		return "the token lacks the security_events scope or access to the repository"
+	}
+}
+
+// scanningDisabled reports whether err is a GitHub response saying that code
+// scanning is not enabled for the repository.
+func scanningDisabled(err error) bool {
+	var respErr *github.ErrorResponse
+	if !errors.As(err, &respErr) {
+		return false
+	}
+
+	message := strings.ToLower(respErr.Message)
+	for _, disabled := range scanningDisabledMessages {
+		if strings.Contains(message, disabled) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyError wraps errors for 401, 403 and 404 responses in an *APIError.
+// Rate limit errors and other failures are returned unchanged.
+func classifyError(err error) error {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateErr) || errors.As(err, &abuseErr) {
+		return err
+	}
+
+	var respErr *github.ErrorResponse
+	if !errors.As(err, &respErr) || respErr.Response == nil {
+		return err
+	}
+
+	switch respErr.Response.StatusCode {
+	case http.StatusNotFound, http.StatusUnauthorized, http.StatusForbidden:
+		return &APIError{StatusCode: respErr.Response.StatusCode, Err: err}
+	}
+	return err
+}
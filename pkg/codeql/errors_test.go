@@ -0,0 +1,64 @@
+package codeql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestErrorClassification(t *testing.T) {
+	sentinels := []error{ErrNotFound, ErrUnauthorized, ErrForbidden, ErrScanningDisabled}
+
+	tests := []struct {
+		name    string
+		status  int
+		message string
+		// want are the sentinel errors the error must match; it must not
+		// match the others
+		want       []error
+		wantStatus int
+	}{
+		{name: "not found", status: http.StatusNotFound, message: "Not Found",
+			want: []error{ErrNotFound}, wantStatus: http.StatusNotFound},
+		{name: "no analysis", status: http.StatusNotFound, message: "No analysis found",
+			want: []error{ErrNotFound, ErrScanningDisabled}, wantStatus: http.StatusNotFound},
+		{name: "forbidden", status: http.StatusForbidden, message: "Resource not accessible by integration",
+			want: []error{ErrForbidden}, wantStatus: http.StatusForbidden},
+		{name: "scanning disabled", status: http.StatusForbidden, message: "Code scanning is not enabled for this repository",
+			want: []error{ErrForbidden, ErrScanningDisabled}, wantStatus: http.StatusForbidden},
+		{name: "advanced security disabled", status: http.StatusForbidden, message: "Advanced Security must be enabled for this repository to use code scanning.",
+			want: []error{ErrForbidden, ErrScanningDisabled}, wantStatus: http.StatusForbidden},
+		{name: "unauthorized", status: http.StatusUnauthorized, message: "Bad credentials",
+			want: []error{ErrUnauthorized}, wantStatus: http.StatusUnauthorized},
+		{name: "server error", status: http.StatusInternalServerError, message: "Internal Server Error"},
+		{name: "bad gateway", status: http.StatusBadGateway, message: "Bad Gateway"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _, _ := newTestClient(fakeResponse{status: tt.status, message: tt.message})
+
+			_, err := client.GetAlert(context.Background(), "org", "repo", 1)
+			if err == nil {
+				t.Fatal("GetAlert() error = nil, want an error")
+			}
+
+			for _, sentinel := range sentinels {
+				want := slices.Contains(tt.want, sentinel)
+				if got := errors.Is(err, sentinel); got != want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", err, sentinel, got, want)
+				}
+			}
+
+			var apiErr *APIError
+			if got := errors.As(err, &apiErr); got != (tt.wantStatus != 0) {
+				t.Fatalf("errors.As(%v, *APIError) = %v, want %v", err, got, tt.wantStatus != 0)
+			}
+			if apiErr != nil && apiErr.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
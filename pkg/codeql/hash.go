@@ -0,0 +1,37 @@
+package codeql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// AlertHash returns a stable SHA-256 hash of the fields that identify an alert
+// and its location, as lowercase hex. Comparing hashes between two reports
+// shows which alerts were added, removed or moved.
+//
+// The hash is computed over these fields, each followed by a newline:
+// the owner and repository in lower case, the alert number, the rule ID, the
+// file path, and the start line, start column, end line and end column. Numbers
+// are written in decimal. This canonical form must not change, so that
+// hashes from different versions can be compared.
+func AlertHash(alert Alert) string {
+	fields := []string{
+		strings.ToLower(alert.Owner),
+		strings.ToLower(alert.Repo),
+		strconv.Itoa(alert.ID),
+		alert.RuleID,
+		alert.FilePath,
+		strconv.Itoa(alert.StartLine),
+		strconv.Itoa(alert.StartColumn),
+		strconv.Itoa(alert.EndLine),
+		strconv.Itoa(alert.EndColumn),
+	}
+
+	h := sha256.New()
+	for _, field := range fields {
+		h.Write([]byte(field + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
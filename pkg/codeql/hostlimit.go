@@ -0,0 +1,76 @@
+package codeql
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// hostLimitTransport is an http.RoundTripper that allows at most limit
+// requests to each host at a time. A Client only sends requests to the host of
+// its BaseURL, so in practice this caps concurrent requests to that one host;
+// slots are kept per host so that redirects elsewhere do not take them up. A
+// request holds its slot until its response body is closed.
+type hostLimitTransport struct {
+	base  http.RoundTripper
+	limit int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// newHostLimitTransport creates a hostLimitTransport allowing limit
+// concurrent requests per host.
+func newHostLimitTransport(base http.RoundTripper, limit int) *hostLimitTransport {
+	return &hostLimitTransport{
+		base:  base,
+		limit: limit,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+// RoundTrip waits for a free slot for the request's host, or for the request
+// to be canceled, then sends the request.
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	slots := t.hostSlots(req.URL.Hostname())
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := sync.OnceFunc(func() { <-slots })
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// hostSlots returns the semaphore limiting requests to host.
+func (t *hostLimitTransport) hostSlots(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	slots, ok := t.slots[host]
+	if !ok {
+		slots = make(chan struct{}, t.limit)
+		t.slots[host] = slots
+	}
+	return slots
+}
+
+// releasingBody is a response body that frees its request's slot when closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the body and frees the slot.
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,164 @@
+package codeql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// Instance is an occurrence of an alert on a single git reference, such as a
+// branch or pull request.
+type Instance struct {
+	Ref         string
+	State       string
+	CommitSHA   string
+	FilePath    string
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+	Category    string
+}
+
+// GetAlertInstances fetches every instance of a CodeQL alert, one per git
+// reference the alert was found on, following pagination until all pages
+// have been read. If ref is not empty, only the instance on that git
+// reference is returned.
+func (c *Client) GetAlertInstances(ctx context.Context, owner, repo string, alertNumber int64, ref string) ([]Instance, error) {
+	c.logger.Info("Listing alert instances", AlertAttrs(owner, repo, alertNumber)...)
+
+	listOpts := &github.AlertInstancesListOptions{
+		Ref:         ref,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var instances []Instance
+	for {
+		var page []*github.MostRecentInstance
+		var resp *github.Response
+		err := c.do(ctx, func(ctx context.Context) (*github.Response, error) {
+			var err error
+			page, resp, err = c.services.CodeScanning.ListAlertInstances(ctx, owner, repo, alertNumber, listOpts)
+			return resp, err
+		}, AlertAttrs(owner, repo, alertNumber)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list alert instances: %w", err)
+		}
+
+		for _, instance := range page {
+			instances = append(instances, newInstance(instance))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.ListOptions.Page = resp.NextPage
+	}
+
+	if ref == "" {
+		c.storeInstanceCount(owner, repo, alertNumber, len(instances))
+	}
+	return instances, nil
+}
+
+// CountAlertInstances returns the number of instances of a CodeQL alert, one
+// per git reference it was found on. Counts are kept for the lifetime of the
+// client, so each alert's instances are only listed once, including by
+// GetAlertInstances for every reference.
+func (c *Client) CountAlertInstances(ctx context.Context, owner, repo string, alertNumber int64) (int, error) {
+	c.mu.Lock()
+	count, ok := c.instanceCounts[instanceCountKey(owner, repo, alertNumber)]
+	c.mu.Unlock()
+	if ok {
+		return count, nil
+	}
+
+	instances, err := c.GetAlertInstances(ctx, owner, repo, alertNumber, "")
+	if err != nil {
+		return 0, err
+	}
+	return len(instances), nil
+}
+
+// storeInstanceCount keeps the number of instances of an alert for
+// CountAlertInstances.
+func (c *Client) storeInstanceCount(owner, repo string, alertNumber int64, count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.instanceCounts == nil {
+		c.instanceCounts = make(map[string]int)
+	}
+	c.instanceCounts[instanceCountKey(owner, repo, alertNumber)] = count
+}
+
+// instanceCountKey identifies an alert in the instance counts. Owner and
+// repository names are not case-sensitive.
+func instanceCountKey(owner, repo string, alertNumber int64) string {
+	return strings.ToLower(owner+"/"+repo) + "#" + strconv.FormatInt(alertNumber, 10)
+}
+
+// GetAlertAtRef fetches a CodeQL alert by its number, with the location, state
+// and commit of its instance on the given git reference, such as
+// refs/heads/main. The API returns alerts with their most recent instance, so
+// this takes a second request. If ref is empty, it is the same as GetAlert.
+func (c *Client) GetAlertAtRef(ctx context.Context, owner, repo string, alertNumber int64, ref string) (*Alert, error) {
+	alert, err := c.GetAlert(ctx, owner, repo, alertNumber)
+	if err != nil || ref == "" {
+		return alert, err
+	}
+
+	instances, err := c.GetAlertInstances(ctx, owner, repo, alertNumber, ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("alert #%d for %s/%s has no instance on %s", alertNumber, owner, repo, ref)
+	}
+
+	atRef := alert.WithInstance(instances[0])
+	return &atRef, nil
+}
+
+// WithInstance returns a copy of the alert describing the given instance:
+// its ref, commit, state, location and category replace those of the most
+// recent instance.
+func (a Alert) WithInstance(instance Instance) Alert {
+	a.Ref = instance.Ref
+	a.CommitSHA = instance.CommitSHA
+	a.State = instance.State
+	a.FilePath = instance.FilePath
+	a.StartLine = instance.StartLine
+	a.StartColumn = instance.StartColumn
+	a.EndLine = instance.EndLine
+	a.EndColumn = instance.EndColumn
+	a.Category = instance.Category
+	return a
+}
+
+// instanceCategory returns the analysis category of an instance, or its
+// analysis key for analyses uploaded without a category.
+func instanceCategory(instance *github.MostRecentInstance) string {
+	if category := instance.GetCategory(); category != "" {
+		return category
+	}
+	return instance.GetAnalysisKey()
+}
+
+// newInstance converts a go-github alert instance into an Instance.
+func newInstance(instance *github.MostRecentInstance) Instance {
+	location := instance.GetLocation()
+	return Instance{
+		Ref:         instance.GetRef(),
+		State:       instance.GetState(),
+		CommitSHA:   instance.GetCommitSHA(),
+		FilePath:    location.GetPath(),
+		StartLine:   location.GetStartLine(),
+		StartColumn: location.GetStartColumn(),
+		EndLine:     location.GetEndLine(),
+		EndColumn:   location.GetEndColumn(),
+		Category:    instanceCategory(instance),
+	}
+}
@@ -0,0 +1,113 @@
+package codeql
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// lowRateRemaining is the number of remaining requests below which the
+// limiter starts reducing concurrency.
+const lowRateRemaining = 500
+
+// Limiter bounds how many requests run at once. The limit scales between a
+// minimum and maximum number of workers with the remaining rate limit budget,
+// so parallel workers slow down before they exhaust the rate limit or trigger
+// secondary rate limits.
+type Limiter struct {
+	minWorkers int
+	maxWorkers int
+	logger     *slog.Logger
+
+	mu      sync.Mutex
+	limit   int
+	active  int
+	changed chan struct{}
+}
+
+// NewLimiter creates a Limiter allowing between minWorkers and maxWorkers
+// concurrent requests, starting at maxWorkers.
+func NewLimiter(minWorkers, maxWorkers int, logger *slog.Logger) *Limiter {
+	return &Limiter{
+		minWorkers: minWorkers,
+		maxWorkers: maxWorkers,
+		logger:     logger,
+		limit:      maxWorkers,
+		changed:    make(chan struct{}),
+	}
+}
+
+// Acquire blocks until a request may start or ctx is done. Every successful
+// call must be paired with a call to Release.
+func (l *Limiter) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.active < l.limit {
+			l.active++
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Release marks a request started by Acquire as finished.
+func (l *Limiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	l.notify()
+}
+
+// Limit returns the number of requests currently allowed to run at once.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Adjust sets the limit from the rate limit reported by the latest response.
+func (l *Limiter) Adjust(rate github.Rate) {
+	limit := workersFor(rate, l.minWorkers, l.maxWorkers)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit == l.limit {
+		return
+	}
+	l.logger.Info("Adjusting concurrency",
+		append([]any{slog.Int("from_workers", l.limit), slog.Int("to_workers", limit)}, RateAttrs(rate)...)...)
+	l.limit = limit
+	l.notify()
+}
+
+// notify wakes every goroutine waiting in Acquire. It must be called with l.mu held.
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// workersFor returns how many workers to run for the given rate limit:
+// maxWorkers while at least lowRateRemaining requests remain, scaling down to
+// minWorkers as the budget runs out. Once the reset time has passed the budget
+// is replenished, so maxWorkers is returned again.
+func workersFor(rate github.Rate, minWorkers, maxWorkers int) int {
+	if rate.Reset.IsZero() || !rate.Reset.After(time.Now()) || rate.Remaining >= lowRateRemaining {
+		return maxWorkers
+	}
+
+	workers := maxWorkers * rate.Remaining / lowRateRemaining
+	return min(max(workers, minWorkers), maxWorkers)
+}
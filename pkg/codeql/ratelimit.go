@@ -0,0 +1,69 @@
+package codeql
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// LowRateLimit is the number of remaining requests below which the rate limit
+// is reported as low.
+const LowRateLimit = 10
+
+// defaultSecondaryRateLimitWait is how long to wait after hitting a secondary
+// rate limit when GitHub does not say how long to wait.
+const defaultSecondaryRateLimitWait = time.Minute
+
+// secondaryRateLimitWait reports whether a failed request hit a secondary
+// (abuse detection) rate limit and, if so, how long to wait before retrying.
+func secondaryRateLimitWait(resp *github.Response, err error) (time.Duration, bool) {
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if wait := abuseErr.GetRetryAfter(); wait > 0 {
+			return wait, true
+		}
+		return defaultSecondaryRateLimitWait, true
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusForbidden {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return wait, true
+		}
+	}
+
+	return 0, false
+}
+
+// tooManyRequestsWait reports whether a failed request was answered with 429
+// Too Many Requests, as some proxies in front of GitHub do instead of 403, and
+// if so how long its Retry-After header asks to wait. The wait is zero if the
+// header is missing or invalid.
+func tooManyRequestsWait(resp *github.Response) (time.Duration, bool) {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	wait, _ := parseRetryAfter(resp.Header.Get("Retry-After"))
+	return wait, true
+}
+
+// parseRetryAfter parses a Retry-After header given as a number of seconds or
+// as an HTTP date. A date in the past means no wait.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(date), 0), true
+}
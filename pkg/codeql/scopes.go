@@ -0,0 +1,67 @@
+package codeql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// ErrMissingScope means the token lacks the OAuth scopes needed to read code
+// scanning alerts.
+var ErrMissingScope = errors.New("token is missing the security_events scope")
+
+// CheckTokenScopes makes a single request that does not count against the
+// rate limit and checks the token's OAuth scopes, so a token that cannot read
+// alerts is rejected before any work is done. Fine-grained tokens and GitHub
+// App tokens do not report scopes, so they are not checked. The core rate
+// limit in the response is returned by RateStatus afterwards.
+func (c *Client) CheckTokenScopes(ctx context.Context) error {
+	var limits *github.RateLimits
+	var resp *github.Response
+	err := c.do(ctx, func(ctx context.Context) (*github.Response, error) {
+		var err error
+		limits, resp, err = c.services.RateLimit.Get(ctx)
+		return resp, err
+	})
+	// GitHub Enterprise Server returns 404 when rate limiting is disabled
+	if errors.Is(err, ErrNotFound) {
+		c.logger.Info("Rate limit endpoint not available, skipping the scope check")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check token: %w", err)
+	}
+	// The headers of this endpoint may not describe the core API
+	if core := limits.GetCore(); core != nil {
+		c.recordRate(*core)
+	}
+
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		c.logger.Info("Token does not report OAuth scopes, skipping the scope check")
+		return nil
+	}
+
+	var scopes []string
+	for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	c.logger.Info("Token scopes", slog.String("scopes", strings.Join(scopes, ", ")))
+
+	switch {
+	case slices.Contains(scopes, "security_events"), slices.Contains(scopes, "repo"):
+		return nil
+	case slices.Contains(scopes, "public_repo"):
+		c.logger.Warn("token only has the public_repo scope, so alerts in private repositories cannot be read")
+		return nil
+	default:
+		return fmt.Errorf("%w (token scopes: %s)", ErrMissingScope, strings.Join(scopes, ", "))
+	}
+}
@@ -0,0 +1,46 @@
+package codeql
+
+import (
+	"context"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// CodeScanningAPI is the part of the GitHub code scanning API used by the
+// client. It is implemented by *github.CodeScanningService.
+type CodeScanningAPI interface {
+	GetAlert(ctx context.Context, owner, repo string, id int64) (*github.Alert, *github.Response, error)
+	ListAlertsForRepo(ctx context.Context, owner, repo string, opts *github.AlertListOptions) ([]*github.Alert, *github.Response, error)
+	ListAlertsForOrg(ctx context.Context, org string, opts *github.AlertListOptions) ([]*github.Alert, *github.Response, error)
+	ListAlertInstances(ctx context.Context, owner, repo string, id int64, opts *github.AlertInstancesListOptions) ([]*github.MostRecentInstance, *github.Response, error)
+	UpdateAlert(ctx context.Context, owner, repo string, id int64, stateInfo *github.CodeScanningAlertState) (*github.Alert, *github.Response, error)
+}
+
+// RepositoriesAPI is the part of the GitHub repositories API used by the
+// client. It is implemented by *github.RepositoriesService.
+type RepositoriesAPI interface {
+	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error)
+}
+
+// RateLimitAPI is the part of the GitHub rate limit API used by the client.
+// It is implemented by *github.RateLimitService.
+type RateLimitAPI interface {
+	Get(ctx context.Context) (*github.RateLimits, *github.Response, error)
+}
+
+// Services are the GitHub APIs a Client calls. Tests can provide fakes
+// through NewClientWithServices instead of calling GitHub.
+type Services struct {
+	CodeScanning CodeScanningAPI
+	Repositories RepositoriesAPI
+	RateLimit    RateLimitAPI
+}
+
+// newServices returns the services of a GitHub API client.
+func newServices(ghClient *github.Client) Services {
+	return Services{
+		CodeScanning: ghClient.CodeScanning,
+		Repositories: ghClient.Repositories,
+		RateLimit:    ghClient.RateLimit,
+	}
+}
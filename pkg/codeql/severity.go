@@ -0,0 +1,15 @@
+package codeql
+
+// severityRanks orders the CodeQL security severity levels, most severe highest.
+var severityRanks = map[string]int{
+	"critical": 4,
+	"high":     3,
+	"medium":   2,
+	"low":      1,
+}
+
+// SeverityRank returns the rank of a security severity level, where a higher
+// rank is more severe. Empty or unrecognized levels rank 0.
+func SeverityRank(level string) int {
+	return severityRanks[level]
+}
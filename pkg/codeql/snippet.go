@@ -0,0 +1,80 @@
+package codeql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/go-github/v72/github"
+)
+
+// MaxSnippetLines is the number of lines a snippet is truncated to.
+const MaxSnippetLines = 20
+
+// snippetTruncated marks the end of a snippet that was cut short.
+const snippetTruncated = "..."
+
+// GetSnippet fetches the file an alert is located in, at the alert's commit,
+// and returns the lines from its start line to its end line. Regions longer
+// than MaxSnippetLines are truncated. Alerts without a location have no snippet.
+func (c *Client) GetSnippet(ctx context.Context, alert Alert) (string, error) {
+	if alert.FilePath == "" || alert.StartLine <= 0 {
+		return "", nil
+	}
+
+	ref := alert.CommitSHA
+	if ref == "" {
+		ref = alert.Ref
+	}
+
+	c.logger.Info("Fetching alert snippet",
+		append(AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID)), slog.String("path", alert.FilePath))...)
+
+	var file *github.RepositoryContent
+	err := c.do(ctx, func(ctx context.Context) (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		file, _, resp, err = c.services.Repositories.GetContents(ctx, alert.Owner, alert.Repo, alert.FilePath,
+			&github.RepositoryContentGetOptions{Ref: ref})
+		return resp, err
+	}, AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID))...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get contents of %s: %w", alert.FilePath, err)
+	}
+	if file == nil {
+		return "", fmt.Errorf("failed to get contents of %s: not a file", alert.FilePath)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return "", fmt.Errorf("failed to decode contents of %s: %w", alert.FilePath, err)
+	}
+
+	return extractLines(content, alert.StartLine, alert.EndLine), nil
+}
+
+// extractLines returns the lines of content from startLine to endLine,
+// counting from 1, truncated to MaxSnippetLines. An endLine before startLine
+// selects the start line only.
+func extractLines(content string, startLine, endLine int) string {
+	lines := strings.Split(content, "\n")
+	if startLine > len(lines) {
+		return ""
+	}
+	endLine = min(max(endLine, startLine), len(lines))
+
+	selected := lines[startLine-1 : endLine]
+	truncated := len(selected) > MaxSnippetLines
+	if truncated {
+		selected = selected[:MaxSnippetLines]
+	}
+
+	for i, line := range selected {
+		selected[i] = strings.TrimSuffix(line, "\r")
+	}
+	if truncated {
+		selected = append(selected, snippetTruncated)
+	}
+	return strings.Join(selected, "\n")
+}
@@ -0,0 +1,60 @@
+package codeql
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// traceTransport is an http.RoundTripper that logs every request and
+// response passing through it, for debugging API errors.
+type traceTransport struct {
+	base   http.RoundTripper
+	logger *slog.Logger
+}
+
+// RoundTrip logs the request method, URL and headers, then the response
+// status and how long the request took.
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.logger.Debug("HTTP request", slog.String("method", req.Method), slog.String("url", req.URL.String()),
+		slog.String("headers", redactHeaders(req.Header)))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.logger.Debug("HTTP request failed", slog.String("method", req.Method), slog.String("url", req.URL.String()),
+			slog.Duration("elapsed", elapsed), slog.Any("error", err))
+		return nil, err
+	}
+
+	attrs := []any{slog.String("method", req.Method), slog.String("url", req.URL.String()),
+		slog.String("status", resp.Status), slog.Duration("elapsed", elapsed)}
+	// GitHub support can look up a request by its ID
+	if id := resp.Header.Get("X-GitHub-Request-Id"); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	t.logger.Debug("HTTP response", attrs...)
+	return resp, nil
+}
+
+// redactHeaders formats headers for logging with credentials replaced.
+func redactHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		value := strings.Join(header.Values(name), ", ")
+		if strings.EqualFold(name, "Authorization") {
+			value = "[REDACTED]"
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return "[" + strings.Join(parts, "; ") + "]"
+}
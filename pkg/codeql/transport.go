@@ -0,0 +1,67 @@
+package codeql
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// newTransport creates the HTTP transport used for API requests. Requests go
+// through opts.Proxy, or else the proxy given by the HTTPS_PROXY and NO_PROXY
+// environment variables, and server certificates are verified against the
+// system roots plus the certificates in opts.CACertFile. At most
+// opts.WorkersPerHost requests run against the API host at a time.
+func newTransport(opts Options, logger *slog.Logger) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		logger.Info("Using proxy", slog.String("proxy", proxyURL.Redacted()))
+	}
+
+	if opts.CACertFile != "" {
+		pool, err := loadCACerts(opts.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		logger.Info("Trusting CA certificates", slog.String("file", opts.CACertFile))
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if opts.WorkersPerHost > 0 {
+		roundTripper = newHostLimitTransport(roundTripper, opts.WorkersPerHost)
+		logger.Info("Limiting concurrent requests to the API host", slog.Int("workers_per_host", opts.WorkersPerHost))
+	}
+
+	if opts.Trace {
+		return &traceTransport{base: roundTripper, logger: logger}, nil
+	}
+	return roundTripper, nil
+}
+
+// loadCACerts returns the system certificate pool with the PEM certificates
+// in path added.
+func loadCACerts(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("failed to load CA certificate file %s: no PEM certificates found", path)
+	}
+	return pool, nil
+}
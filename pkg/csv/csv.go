@@ -2,41 +2,121 @@
 package csv
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 )
 
+// GzipExtension is the file extension that makes a Writer compress its output.
+const GzipExtension = ".gz"
+
+// gzipMagic is the header every gzip stream starts with.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// byteOrderMark is the UTF-8 byte order mark that spreadsheet applications such
+// as Excel write at the start of exported CSV files.
+const byteOrderMark = "\ufeff"
+
+// ErrEmptyFile is returned when reading a file without even a header row.
+var ErrEmptyFile = errors.New("file is empty, expected a header row")
+
+// Options configures how CSV files are read and written.
+type Options struct {
+	// Delimiter is the field delimiter. When zero, a comma is used.
+	Delimiter rune
+	// LazyQuotes allows quotes to appear in unquoted fields and non-doubled
+	// quotes to appear in quoted fields when reading.
+	LazyQuotes bool
+	// SkipBadRows skips rows whose length does not match the header row
+	// instead of failing. Skipped rows are available from SkippedRows.
+	SkipBadRows bool
+	// TrimValues removes leading and trailing whitespace from every value
+	// when reading. Header names are always trimmed.
+	TrimValues bool
+	// EscapeFormulas prefixes values that spreadsheet applications would run
+	// as formulas with a single quote when writing. See EscapeFormula.
+	EscapeFormulas bool
+	// Append makes Open add records to the end of an existing file instead of
+	// truncating it. The header row is only written if the file is empty;
+	// otherwise the file's header row must match the writer's headers.
+	Append bool
+	// NoHeader leaves out the header row when writing. Appending to a file
+	// then adds records without checking the file's columns.
+	NoHeader bool
+}
+
 // Reader handles reading and parsing CSV files.
 type Reader struct {
 	filePath string
+	opts     Options
+	headers  []string
+	skipped  []error
 }
 
-// NewReader creates a new CSV reader for the specified file.
-func NewReader(filePath string) *Reader {
+// StdinPath is the file path that makes a Reader read from standard input.
+const StdinPath = "-"
+
+// NewReader creates a new CSV reader for the specified file. If the file path
+// is StdinPath, the CSV is read from standard input instead.
+func NewReader(filePath string, opts Options) *Reader {
 	return &Reader{
 		filePath: filePath,
+		opts:     opts,
 	}
 }
 
 // ReadAllWithHeaders reads all records from a CSV file and returns them as a slice of maps.
-// Each map represents a row, with keys being the column headers.
+// Each map represents a row, with keys being the column headers. Gzip-compressed
+// files are decompressed transparently.
 func (r *Reader) ReadAllWithHeaders() ([]map[string]string, error) {
-	f, err := os.Open(r.filePath)
+	var f io.Reader = os.Stdin
+	if r.filePath != StdinPath {
+		file, err := os.Open(r.filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s: %w", r.filePath, err)
+		}
+		defer file.Close()
+		f = file
+	}
+
+	input, err := decompress(f)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", r.filePath, err)
+		return nil, fmt.Errorf("failed to read file %s: %w", r.filePath, err)
 	}
-	defer f.Close()
 
-	reader := csv.NewReader(f)
+	reader := csv.NewReader(input)
+	if r.opts.Delimiter != 0 {
+		reader.Comma = r.opts.Delimiter
+	}
+	reader.LazyQuotes = r.opts.LazyQuotes
+	// Row lengths are checked below so bad rows can be skipped
+	reader.FieldsPerRecord = -1
 
 	// Read headers
 	headers, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrEmptyFile
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CSV headers: %w", err)
 	}
 
+	// Surrounding whitespace and a byte order mark would otherwise become part
+	// of the column names, so lookups by name would find nothing
+	headers[0] = strings.TrimPrefix(headers[0], byteOrderMark)
+	for i, header := range headers {
+		headers[i] = strings.TrimSpace(header)
+	}
+	r.headers = headers
+	r.skipped = nil
+
 	var records []map[string]string
 
 	// Read rows
@@ -50,13 +130,23 @@ func (r *Reader) ReadAllWithHeaders() ([]map[string]string, error) {
 		}
 
 		if len(row) != len(headers) {
-			return nil, fmt.Errorf("row length (%d) does not match header length (%d): %v", len(row), len(headers), row)
+			line, _ := reader.FieldPos(0)
+			err := fmt.Errorf("line %d: row length (%d) does not match header length (%d): %v", line, len(row), len(headers), row)
+			if !r.opts.SkipBadRows {
+				return nil, err
+			}
+			r.skipped = append(r.skipped, err)
+			continue
 		}
 
 		// Build map for this row
 		rowMap := make(map[string]string)
 		for i, header := range headers {
-			rowMap[header] = row[i]
+			value := row[i]
+			if r.opts.TrimValues {
+				value = strings.TrimSpace(value)
+			}
+			rowMap[header] = value
 		}
 		records = append(records, rowMap)
 	}
@@ -64,40 +154,265 @@ func (r *Reader) ReadAllWithHeaders() ([]map[string]string, error) {
 	return records, nil
 }
 
+// Headers returns the header row read by ReadAllWithHeaders.
+func (r *Reader) Headers() []string {
+	return r.headers
+}
+
+// SkippedRows returns an error describing each row skipped by
+// ReadAllWithHeaders when SkipBadRows is set.
+func (r *Reader) SkippedRows() []error {
+	return r.skipped
+}
+
+// StdoutPath is the file path that makes a Writer write to standard output.
+const StdoutPath = "-"
+
+// decompress returns a reader that decompresses r if it starts with the gzip
+// header, and otherwise reads r unchanged.
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil || !bytes.Equal(magic, gzipMagic) {
+		return buffered, nil
+	}
+
+	gz, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	return gz, nil
+}
+
 // Writer handles writing CSV data to files.
 type Writer struct {
 	filePath string
 	headers  []string
+	opts     Options
+	file     *os.File
+	gzip     *gzip.Writer
+	writer   *csv.Writer
 }
 
 // NewWriter creates a new CSV writer for the specified file.
-func NewWriter(filePath string, headers []string) *Writer {
+func NewWriter(filePath string, headers []string, opts Options) *Writer {
 	return &Writer{
 		filePath: filePath,
 		headers:  headers,
+		opts:     opts,
 	}
 }
 
-// WriteAll writes all records to a CSV file.
-func (w *Writer) WriteAll(records [][]string) error {
-	f, err := os.Create(w.filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", w.filePath, err)
+// Open creates the CSV file and writes the header row. If the file path is
+// StdoutPath, the CSV is written to standard output instead. Files ending in
+// GzipExtension are gzip-compressed; appending to one adds a new gzip member,
+// which gzip readers decompress as a single stream.
+func (w *Writer) Open() error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if w.opts.Append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	f := os.Stdout
+	if w.filePath != StdoutPath {
+		var err error
+		f, err = os.OpenFile(w.filePath, flags, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create file %s: %w", w.filePath, err)
+		}
 	}
-	defer f.Close()
 
-	writer := csv.NewWriter(f)
-	defer writer.Flush()
+	w.file = f
+	var output io.Writer = f
+	if strings.HasSuffix(strings.ToLower(w.filePath), GzipExtension) {
+		w.gzip = gzip.NewWriter(f)
+		output = w.gzip
+	}
+	w.writer = csv.NewWriter(output)
+	if w.opts.Delimiter != 0 {
+		w.writer.Comma = w.opts.Delimiter
+	}
+
+	if w.opts.NoHeader {
+		return nil
+	}
+
+	// An existing file already has its header row
+	if w.opts.Append {
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to stat file %s: %w", w.filePath, err)
+		}
+		if info.Size() > 0 {
+			if err := w.checkHeaders(); err != nil {
+				f.Close()
+				return err
+			}
+			return nil
+		}
+	}
 
 	// Write headers
-	if err := writer.Write(w.headers); err != nil {
+	if err := w.writer.Write(w.headers); err != nil {
+		if f != os.Stdout {
+			f.Close()
+		}
 		return fmt.Errorf("failed to write CSV headers: %w", err)
 	}
 
+	return w.Flush()
+}
+
+// checkHeaders checks that the header row of the existing file matches the
+// writer's headers, so appended records line up with the existing columns.
+func (w *Writer) checkHeaders() error {
+	f, err := os.Open(w.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", w.filePath, err)
+	}
+	defer f.Close()
+
+	input, err := decompress(f)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", w.filePath, err)
+	}
+
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	if w.opts.Delimiter != 0 {
+		reader.Comma = w.opts.Delimiter
+	}
+
+	existing, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read headers of %s: %w", w.filePath, err)
+	}
+
+	if !slices.Equal(existing, w.headers) {
+		return fmt.Errorf("cannot append to %s: its columns %q do not match the report columns %q", w.filePath, existing, w.headers)
+	}
+	return nil
+}
+
+// formulaPrefixes are the leading characters that make spreadsheet
+// applications treat a cell as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// EscapeFormula prefixes value with a single quote if it starts with a
+// character that makes spreadsheet applications treat it as a formula, so
+// the value is shown as text instead of being run (CSV injection).
+func EscapeFormula(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Write writes a single record and flushes it to the file, so the file
+// remains a valid CSV if the process stops before Close is called.
+func (w *Writer) Write(record []string) error {
+	if err := w.writer.Write(w.escape(record)); err != nil {
+		return fmt.Errorf("failed to write CSV record: %w", err)
+	}
+
+	return w.Flush()
+}
+
+// escape returns record with formulas escaped if EscapeFormulas is set.
+func (w *Writer) escape(record []string) []string {
+	return escapeRecord(record, w.opts)
+}
+
+// escapeRecord returns record with formulas escaped if opts.EscapeFormulas is set.
+func escapeRecord(record []string, opts Options) []string {
+	if !opts.EscapeFormulas {
+		return record
+	}
+
+	escaped := make([]string, len(record))
+	for i, value := range record {
+		escaped[i] = EscapeFormula(value)
+	}
+	return escaped
+}
+
+// Flush writes any buffered data to the file.
+func (w *Writer) Flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV: %w", err)
+	}
+	if w.gzip != nil {
+		if err := w.gzip.Flush(); err != nil {
+			return fmt.Errorf("failed to flush compressed CSV: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close flushes any buffered data and closes the file. Standard output is
+// left open.
+func (w *Writer) Close() error {
+	flushErr := w.Flush()
+	if w.gzip != nil {
+		// Closing the gzip stream writes its trailer
+		if err := w.gzip.Close(); err != nil && flushErr == nil {
+			flushErr = fmt.Errorf("failed to finish compressed CSV: %w", err)
+		}
+	}
+	if w.file == os.Stdout {
+		return flushErr
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", w.filePath, err)
+	}
+	return flushErr
+}
+
+// WriteAll writes all records to a CSV file.
+func (w *Writer) WriteAll(records [][]string) error {
+	if err := w.Open(); err != nil {
+		return err
+	}
+
 	// Write records
-	if err := writer.WriteAll(records); err != nil {
+	escaped := make([][]string, len(records))
+	for i, record := range records {
+		escaped[i] = w.escape(record)
+	}
+	if err := w.writer.WriteAll(escaped); err != nil {
+		if w.file != os.Stdout {
+			w.file.Close()
+		}
 		return fmt.Errorf("failed to write CSV records: %w", err)
 	}
 
+	return w.Close()
+}
+
+// Encode writes headers and records to out as CSV using the delimiter,
+// formula escaping and NoHeader setting of opts.
+func Encode(out io.Writer, headers []string, records [][]string, opts Options) error {
+	writer := csv.NewWriter(out)
+	if opts.Delimiter != 0 {
+		writer.Comma = opts.Delimiter
+	}
+
+	if !opts.NoHeader {
+		if err := writer.Write(headers); err != nil {
+			return fmt.Errorf("failed to write CSV headers: %w", err)
+		}
+	}
+	for _, record := range records {
+		if err := writer.Write(escapeRecord(record, opts)); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV: %w", err)
+	}
 	return nil
 }
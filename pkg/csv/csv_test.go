@@ -0,0 +1,60 @@
+package csv_test
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		escapeFormulas bool
+		want           string
+	}{
+		{name: "equals", value: "=SUM(A1:A2)", escapeFormulas: true, want: "'=SUM(A1:A2)"},
+		{name: "plus", value: "+1+1", escapeFormulas: true, want: "'+1+1"},
+		{name: "minus", value: "-1+1", escapeFormulas: true, want: "'-1+1"},
+		{name: "at", value: "@SUM(A1)", escapeFormulas: true, want: "'@SUM(A1)"},
+		{name: "tab", value: "\t=1+1", escapeFormulas: true, want: "'\t=1+1"},
+		{name: "carriage return", value: "\r=1+1", escapeFormulas: true, want: "'\r=1+1"},
+		{name: "formula not escaped", value: "=1+1", want: "=1+1"},
+		{name: "plain text", value: "SQL injection", escapeFormulas: true, want: "SQL injection"},
+		{name: "embedded quotes", value: `say "hello"`, escapeFormulas: true, want: `say "hello"`},
+		{name: "embedded commas", value: "a, b, c", escapeFormulas: true, want: "a, b, c"},
+		{name: "embedded newlines", value: "line one\nline two", escapeFormulas: true, want: "line one\nline two"},
+		{name: "escaped with quotes and commas", value: `=HYPERLINK("http://example.com", "x")`, escapeFormulas: true,
+			want: `'=HYPERLINK("http://example.com", "x")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.csv")
+			headers := []string{"Name", "Value"}
+			writer := csvpkg.NewWriter(path, headers, csvpkg.Options{EscapeFormulas: tt.escapeFormulas})
+			if err := writer.WriteAll([][]string{{"first", tt.value}, {"second", "plain"}}); err != nil {
+				t.Fatalf("WriteAll() error = %v", err)
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("failed to open report: %v", err)
+			}
+			defer file.Close()
+			records, err := csv.NewReader(file).ReadAll()
+			if err != nil {
+				t.Fatalf("failed to read report back: %v", err)
+			}
+
+			want := [][]string{headers, {"first", tt.want}, {"second", "plain"}}
+			if !slices.EqualFunc(records, want, slices.Equal) {
+				t.Errorf("read back %q, want %q", records, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package html provides functionality for rendering CodeQL alerts as a
+// self-contained HTML page.
+package html
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// DefaultTitle is the title of a page without a Header.
+const DefaultTitle = "CodeQL Alerts"
+
+// pageTemplate is the page the alerts are rendered into. Its styles and
+// script are inline, so the page has no external assets.
+//
+//go:embed report.html.tmpl
+var pageTemplate string
+
+var page = template.Must(template.New("report").Parse(pageTemplate))
+
+// Header describes the report at the top of the page.
+type Header struct {
+	Title       string
+	GeneratedAt time.Time
+	Version     string
+	Command     string
+}
+
+// Writer renders alerts as an HTML page with a table that can be sorted and
+// filtered in the browser.
+type Writer struct {
+	out    io.Writer
+	header *Header
+}
+
+// NewWriter creates a new HTML writer that writes to out.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{
+		out: out,
+	}
+}
+
+// SetHeader makes WriteAll describe the report above the table.
+func (w *Writer) SetHeader(header Header) {
+	w.header = &header
+}
+
+// pageData is the data the page template is executed with.
+type pageData struct {
+	Title        string
+	Header       *Header
+	GeneratedAt  string
+	Total        int
+	Rows         []row
+	Severities   []string
+	Repositories []string
+}
+
+// row is a table row for a single alert.
+type row struct {
+	Severity     string
+	SeverityRank int
+	Repository   string
+	ID           int
+	RuleID       string
+	Description  string
+	Location     string
+	URL          string
+	State        string
+	CreatedAt    string
+}
+
+// WriteAll writes a page with a table of every alert, most severe first.
+func (w *Writer) WriteAll(alerts []codeql.Alert) error {
+	data := pageData{Title: DefaultTitle, Header: w.header, Total: len(alerts)}
+	if w.header != nil {
+		data.Title = w.header.Title
+		if !w.header.GeneratedAt.IsZero() {
+			data.GeneratedAt = w.header.GeneratedAt.Format(time.RFC3339)
+		}
+	}
+
+	for _, alert := range alerts {
+		r := row{
+			Severity:     alert.Severity,
+			SeverityRank: codeql.SeverityRank(alert.Severity),
+			Repository:   alert.Owner + "/" + alert.Repo,
+			ID:           alert.ID,
+			RuleID:       alert.RuleID,
+			Description:  alert.ShortDesc,
+			Location:     fmt.Sprintf("%s:%d", alert.FilePath, alert.StartLine),
+			URL:          alert.HTMLURL,
+			State:        alert.State,
+		}
+		if !alert.CreatedAt.IsZero() {
+			r.CreatedAt = alert.CreatedAt.Format(time.RFC3339)
+		}
+		data.Rows = append(data.Rows, r)
+
+		if !slices.Contains(data.Severities, r.Severity) {
+			data.Severities = append(data.Severities, r.Severity)
+		}
+		if !slices.Contains(data.Repositories, r.Repository) {
+			data.Repositories = append(data.Repositories, r.Repository)
+		}
+	}
+
+	slices.SortStableFunc(data.Rows, func(a, b row) int {
+		return b.SeverityRank - a.SeverityRank
+	})
+	slices.SortFunc(data.Severities, func(a, b string) int {
+		if ra, rb := codeql.SeverityRank(a), codeql.SeverityRank(b); ra != rb {
+			return rb - ra
+		}
+		return strings.Compare(a, b)
+	})
+	slices.Sort(data.Repositories)
+
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to render HTML: %w", err)
+	}
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+// Package json provides functionality for JSON file operations.
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StdoutPath is the file path that makes a Writer write to standard output.
+const StdoutPath = "-"
+
+// Writer handles writing JSON data to files.
+type Writer struct {
+	filePath string
+	file     *os.File
+	count    int
+}
+
+// NewWriter creates a new JSON writer for the specified file.
+func NewWriter(filePath string) *Writer {
+	return &Writer{
+		filePath: filePath,
+	}
+}
+
+// Open creates the JSON file and starts a JSON array. If the file path is
+// StdoutPath, the JSON is written to standard output instead.
+func (w *Writer) Open() error {
+	f := os.Stdout
+	if w.filePath != StdoutPath {
+		var err error
+		f, err = os.Create(w.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to create file %s: %w", w.filePath, err)
+		}
+	}
+
+	w.file = f
+	w.count = 0
+
+	if _, err := f.WriteString("["); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	return nil
+}
+
+// Write appends v as the next element of the JSON array.
+func (w *Writer) Write(v interface{}) error {
+	data, err := marshal(v, "  ")
+	if err != nil {
+		return err
+	}
+
+	separator := "\n  "
+	if w.count > 0 {
+		separator = ",\n  "
+	}
+
+	if _, err := w.file.WriteString(separator); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	if _, err := w.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	w.count++
+	return nil
+}
+
+// Close ends the JSON array and closes the file. Standard output is left open.
+func (w *Writer) Close() error {
+	end := "]\n"
+	if w.count > 0 {
+		end = "\n]\n"
+	}
+
+	_, writeErr := w.file.WriteString(end)
+	if w.file != os.Stdout {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to close file %s: %w", w.filePath, err)
+		}
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write JSON: %w", writeErr)
+	}
+	return nil
+}
+
+// LinesWriter handles writing JSON Lines files, with one JSON value per line.
+type LinesWriter struct {
+	filePath string
+	file     *os.File
+}
+
+// NewLinesWriter creates a new JSON Lines writer for the specified file.
+func NewLinesWriter(filePath string) *LinesWriter {
+	return &LinesWriter{
+		filePath: filePath,
+	}
+}
+
+// Open creates the JSON Lines file, or opens it for appending if appendToFile
+// is set. If the file path is StdoutPath, the lines are written to standard
+// output instead.
+func (w *LinesWriter) Open(appendToFile bool) error {
+	if w.filePath == StdoutPath {
+		w.file = os.Stdout
+		return nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendToFile {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(w.filePath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", w.filePath, err)
+	}
+
+	w.file = f
+	return nil
+}
+
+// Write writes v as a single line of compact JSON.
+func (w *LinesWriter) Write(v interface{}) error {
+	return EncodeLine(w.file, v)
+}
+
+// Close closes the file. Standard output is left open.
+func (w *LinesWriter) Close() error {
+	if w.file == os.Stdout {
+		return nil
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", w.filePath, err)
+	}
+	return nil
+}
+
+// WriteAll writes v to a JSON file as an indented document.
+func (w *Writer) WriteAll(v interface{}) error {
+	data, err := marshal(v, "")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(w.filePath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to create file %s: %w", w.filePath, err)
+	}
+
+	return nil
+}
+
+// Encode writes v to out as an indented JSON document followed by a newline.
+func Encode(out io.Writer, v interface{}) error {
+	data, err := marshal(v, "")
+	if err != nil {
+		return err
+	}
+
+	if _, err := out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
+
+// EncodeLine writes v to out as a single line of compact JSON. The line is
+// written at once, so lines written concurrently are not interleaved.
+func EncodeLine(out io.Writer, v interface{}) error {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	if _, err := out.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
+
+// marshal encodes v as indented JSON with every line after the first
+// starting with prefix. HTML characters are not escaped so that text
+// fields are preserved exactly.
+func marshal(v interface{}, prefix string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(prefix, "  ")
+
+	if err := encoder.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,141 @@
+// Package markdown provides functionality for rendering CodeQL alerts as
+// GitHub-flavored Markdown.
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// Header is the title block written before the alerts.
+type Header struct {
+	Title       string
+	GeneratedAt time.Time
+	Version     string
+	Command     string
+}
+
+// Writer renders alerts as Markdown tables grouped by severity.
+type Writer struct {
+	out    io.Writer
+	header *Header
+}
+
+// NewWriter creates a new Markdown writer that writes to out.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{
+		out: out,
+	}
+}
+
+// SetHeader makes WriteAll start with a title block describing the report.
+func (w *Writer) SetHeader(header Header) {
+	w.header = &header
+}
+
+// WriteAll writes one section per severity, most severe first, each
+// containing a table of the alerts with that severity.
+func (w *Writer) WriteAll(alerts []codeql.Alert) error {
+	groups := make(map[string][]codeql.Alert)
+	for _, alert := range alerts {
+		groups[alert.Severity] = append(groups[alert.Severity], alert)
+	}
+
+	severities := make([]string, 0, len(groups))
+	for severity := range groups {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		ri, rj := codeql.SeverityRank(severities[i]), codeql.SeverityRank(severities[j])
+		if ri != rj {
+			return ri > rj
+		}
+		return severities[i] < severities[j]
+	})
+
+	var b strings.Builder
+	if w.header != nil {
+		w.writeHeader(&b, len(alerts))
+	}
+	if len(alerts) == 0 {
+		b.WriteString("No alerts found.\n")
+	}
+
+	for i, severity := range severities {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		group := groups[severity]
+		fmt.Fprintf(&b, "## %s (%d)\n\n", severityTitle(severity), len(group))
+		b.WriteString("| Repository | Alert | Description | Location | State |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, alert := range group {
+			fmt.Fprintf(&b, "| %s | %d | %s | %s | %s |\n",
+				escape(alert.Owner+"/"+alert.Repo),
+				alert.ID,
+				escape(alert.ShortDesc),
+				location(alert),
+				escape(alert.State),
+			)
+		}
+	}
+
+	if _, err := io.WriteString(w.out, b.String()); err != nil {
+		return fmt.Errorf("failed to write Markdown: %w", err)
+	}
+	return nil
+}
+
+// writeHeader writes the title block, with the fields of the header that are
+// set and the number of alerts.
+func (w *Writer) writeHeader(b *strings.Builder, total int) {
+	fmt.Fprintf(b, "# %s\n\n", w.header.Title)
+	if !w.header.GeneratedAt.IsZero() {
+		fmt.Fprintf(b, "- Generated at: %s\n", w.header.GeneratedAt.Format(time.RFC3339))
+	}
+	if w.header.Version != "" {
+		fmt.Fprintf(b, "- Version: %s\n", w.header.Version)
+	}
+	if w.header.Command != "" {
+		fmt.Fprintf(b, "- Command: `` %s ``\n", w.header.Command)
+	}
+	fmt.Fprintf(b, "- Total alerts: %d\n\n", total)
+}
+
+// severityTitle returns the section title for a severity level.
+func severityTitle(severity string) string {
+	if severity == "" {
+		return "No Severity"
+	}
+	return strings.ToUpper(severity[:1]) + severity[1:]
+}
+
+// location formats the alert's file and line, linked to the alert when its
+// URL is known.
+func location(alert codeql.Alert) string {
+	text := fmt.Sprintf("%s:%d", alert.FilePath, alert.StartLine)
+	if alert.HTMLURL == "" {
+		return escape(text)
+	}
+	return fmt.Sprintf("[%s](%s)", escapeLinkText(text), alert.HTMLURL)
+}
+
+// escape makes text safe to place in a Markdown table cell.
+func escape(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	text = strings.ReplaceAll(text, "\r\n", " ")
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// escapeLinkText makes text safe to place inside a Markdown link in a table cell.
+func escapeLinkText(text string) string {
+	text = strings.ReplaceAll(text, "[", `\[`)
+	text = strings.ReplaceAll(text, "]", `\]`)
+	return escape(text)
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// checkpoint records which alerts have already been fetched and written, so
+// an interrupted run can be resumed without fetching them again.
+type checkpoint struct {
+	path string
+	file *os.File
+	done map[AlertRef]bool
+}
+
+// openCheckpoint loads the alerts recorded in the checkpoint file at path,
+// creating the file if it does not exist, and opens it for appending.
+func openCheckpoint(path string) (*checkpoint, error) {
+	cp := &checkpoint{
+		path: path,
+		done: make(map[AlertRef]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read checkpoint %s: %w", path, err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		ref, err := parseCheckpointEntry(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint %s line %d: %w", path, line, err)
+		}
+		cp.done[ref.key()] = true
+	}
+
+	cp.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint %s: %w", path, err)
+	}
+
+	return cp, nil
+}
+
+// parseCheckpointEntry parses an owner/repo#number checkpoint line.
+func parseCheckpointEntry(text string) (AlertRef, error) {
+	i := strings.LastIndex(text, "#")
+	if i < 0 {
+		return AlertRef{}, fmt.Errorf("expected owner/repo#number, got %q", text)
+	}
+
+	owner, repo, err := SplitRepository(text[:i])
+	if err != nil {
+		return AlertRef{}, err
+	}
+
+	number, err := strconv.ParseInt(text[i+1:], 10, 64)
+	if err != nil {
+		return AlertRef{}, fmt.Errorf("invalid alert number in %q: %w", text, err)
+	}
+
+	return AlertRef{Owner: owner, Repo: repo, Number: number}, nil
+}
+
+// len returns the number of alerts recorded in the checkpoint.
+func (c *checkpoint) len() int {
+	return len(c.done)
+}
+
+// contains reports whether the alert has already been processed.
+func (c *checkpoint) contains(ref AlertRef) bool {
+	return c.done[ref.key()]
+}
+
+// add records the alert as processed.
+func (c *checkpoint) add(ref AlertRef) error {
+	if _, err := fmt.Fprintln(c.file, ref); err != nil {
+		return fmt.Errorf("failed to update checkpoint %s: %w", c.path, err)
+	}
+	c.done[ref.key()] = true
+	return nil
+}
+
+// Close closes the checkpoint file.
+func (c *checkpoint) Close() error {
+	return c.file.Close()
+}
+
+// skipCheckpointed removes the references already recorded in the checkpoint.
+func (g *generator) skipCheckpointed(refs []AlertRef, cp *checkpoint) []AlertRef {
+	remaining := refs[:0]
+	for _, ref := range refs {
+		if !cp.contains(ref) {
+			remaining = append(remaining, ref)
+		}
+	}
+
+	if done := len(refs) - len(remaining); done > 0 {
+		g.logger.Info("Skipping alerts already recorded in the checkpoint", slog.Int("alerts", done), slog.String("checkpoint", cp.path))
+	}
+	return remaining
+}
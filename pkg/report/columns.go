@@ -0,0 +1,203 @@
+package report
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// column is a column of the output CSV: the Alert field it shows, its
+// header, and how the field is formatted.
+type column struct {
+	field  string
+	header string
+	value  func(codeql.Alert) string
+}
+
+// columns lists every available column in the default order.
+var columns = []column{
+	{"Owner", "Org", func(a codeql.Alert) string { return a.Owner }},
+	{"Repo", "Repo", func(a codeql.Alert) string { return a.Repo }},
+	{"ID", "Alert ID", func(a codeql.Alert) string { return strconv.Itoa(a.ID) }},
+	{"Severity", "Severity", func(a codeql.Alert) string { return a.Severity }},
+	{"ShortDesc", "Short Description", func(a codeql.Alert) string { return a.ShortDesc }},
+	{"FullDesc", "Full Description", func(a codeql.Alert) string { return a.FullDesc }},
+	{"FilePath", "File Path", func(a codeql.Alert) string { return a.FilePath }},
+	{"StartLine", "Start Line", func(a codeql.Alert) string { return strconv.Itoa(a.StartLine) }},
+	{"StartColumn", "Start Column", func(a codeql.Alert) string { return strconv.Itoa(a.StartColumn) }},
+	{"EndLine", "End Line", func(a codeql.Alert) string { return strconv.Itoa(a.EndLine) }},
+	{"EndColumn", "End Column", func(a codeql.Alert) string { return strconv.Itoa(a.EndColumn) }},
+	{"HTMLURL", "HTML URL", func(a codeql.Alert) string { return a.HTMLURL }},
+	{"State", "State", func(a codeql.Alert) string { return a.State }},
+	{"CreatedAt", "Created At", func(a codeql.Alert) string { return FormatTime(a.CreatedAt) }},
+	{"DismissedBy", "Dismissed By", func(a codeql.Alert) string { return a.DismissedBy }},
+	{"DismissedAt", "Dismissed At", func(a codeql.Alert) string { return FormatTime(a.DismissedAt) }},
+	{"DismissedReason", "Dismissed Reason", func(a codeql.Alert) string { return a.DismissedReason }},
+	{"DismissedComment", "Dismissed Comment", func(a codeql.Alert) string { return a.DismissedComment }},
+	{"RuleID", "Rule ID", func(a codeql.Alert) string { return a.RuleID }},
+	{"Tags", "Tags", func(a codeql.Alert) string { return strings.Join(a.Tags, ";") }},
+	{"CWEs", "CWEs", func(a codeql.Alert) string { return strings.Join(a.CWEs, ";") }},
+	{"Tool", "Tool", func(a codeql.Alert) string { return a.Tool }},
+	{"Category", "Category", func(a codeql.Alert) string { return a.Category }},
+	{"Ref", "Ref", func(a codeql.Alert) string { return a.Ref }},
+	{"CommitSHA", "Commit SHA", func(a codeql.Alert) string { return a.CommitSHA }},
+	{"UpdatedAt", "Updated At", func(a codeql.Alert) string { return FormatTime(a.UpdatedAt) }},
+	{"SourceFile", "Source File", func(a codeql.Alert) string { return a.SourceFile }},
+	{"Snippet", "Snippet", func(a codeql.Alert) string { return a.Snippet }},
+	{"Hash", "Row Hash", func(a codeql.Alert) string { return a.Hash }},
+	{"RuleSeverity", "Rule Severity", func(a codeql.Alert) string { return a.RuleSeverity }},
+	{"Help", "Rule Help", func(a codeql.Alert) string { return a.Help }},
+	{"InstanceCount", "Instances", func(a codeql.Alert) string { return strconv.Itoa(a.InstanceCount) }},
+}
+
+// ValidateColumns checks that every name is an Alert field or a column header
+// of the CSV report, compared case-insensitively, and that none is repeated.
+// It also checks that headerNames only renames known columns, and that the
+// headers of the selected columns stay unique once renamed.
+func ValidateColumns(names []string, headerNames map[string]string) error {
+	_, err := resolveColumns(&Config{Columns: names, HeaderNames: headerNames})
+	return err
+}
+
+// resolveColumns returns the columns of the CSV report selected by
+// cfg.Columns, or the default columns if it is not set, with the headers
+// renamed by cfg.HeaderNames. Selecting the SourceFile, Snippet or Hash column
+// turns on cfg.WithSource, cfg.WithSnippet or cfg.WithHash, which fill them in,
+// selecting the RuleSeverity or Help column turns on cfg.WithRuleFields, and
+// selecting the InstanceCount column turns on cfg.WithInstanceCount.
+func resolveColumns(cfg *Config) ([]column, error) {
+	var selected []column
+	if len(cfg.Columns) == 0 {
+		selected = defaultColumns(*cfg)
+	} else {
+		var err error
+		if selected, err = selectColumns(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return renameHeaders(selected, cfg.HeaderNames)
+}
+
+// selectColumns returns the columns named by cfg.Columns, in order.
+func selectColumns(cfg *Config) ([]column, error) {
+	selected := make([]column, 0, len(cfg.Columns))
+	for _, name := range cfg.Columns {
+		col, ok := findColumn(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("invalid column %q: must be one of %s", name, strings.Join(columnFields(), ", "))
+		}
+		if slices.ContainsFunc(selected, func(c column) bool { return c.field == col.field }) {
+			return nil, fmt.Errorf("column %q is selected more than once", col.field)
+		}
+		selected = append(selected, col)
+
+		switch col.field {
+		case "SourceFile":
+			cfg.WithSource = true
+		case "Snippet":
+			cfg.WithSnippet = true
+		case "Hash":
+			cfg.WithHash = true
+		case "RuleSeverity", "Help":
+			cfg.WithRuleFields = true
+		case "InstanceCount":
+			cfg.WithInstanceCount = true
+		}
+	}
+	return selected, nil
+}
+
+// defaultColumns returns every column, leaving out Source File, Snippet and
+// Row Hash unless WithSource, WithSnippet or WithHash is set, the rule columns
+// unless WithRuleFields is set, and Instances unless WithInstanceCount is set.
+func defaultColumns(cfg Config) []column {
+	selected := make([]column, 0, len(columns))
+	for _, col := range columns {
+		if (col.field == "SourceFile" && !cfg.WithSource) || (col.field == "Snippet" && !cfg.WithSnippet) ||
+			(col.field == "Hash" && !cfg.WithHash) ||
+			((col.field == "RuleSeverity" || col.field == "Help") && !cfg.WithRuleFields) ||
+			(col.field == "InstanceCount" && !cfg.WithInstanceCount) {
+			continue
+		}
+		selected = append(selected, col)
+	}
+	return selected
+}
+
+// renameHeaders returns the columns with their headers renamed by headerNames,
+// which is keyed by Alert field or default header. Renaming a column that is
+// not selected has no effect, but every key must name a column.
+func renameHeaders(selected []column, headerNames map[string]string) ([]column, error) {
+	if len(headerNames) == 0 {
+		return selected, nil
+	}
+
+	renamed := make(map[string]string, len(headerNames))
+	for name, header := range headerNames {
+		col, ok := findColumn(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("invalid column %q in header names: must be one of %s", name, strings.Join(columnFields(), ", "))
+		}
+		if header = strings.TrimSpace(header); header == "" {
+			return nil, fmt.Errorf("invalid header name for column %q: must not be empty", col.field)
+		}
+		if _, ok := renamed[col.field]; ok {
+			return nil, fmt.Errorf("column %q is renamed more than once", col.field)
+		}
+		renamed[col.field] = header
+	}
+
+	result := make([]column, 0, len(selected))
+	seen := make(map[string]string, len(selected))
+	for _, col := range selected {
+		if header, ok := renamed[col.field]; ok {
+			col.header = header
+		}
+		if other, ok := seen[col.header]; ok {
+			return nil, fmt.Errorf("columns %q and %q would both have the header %q", other, col.field, col.header)
+		}
+		seen[col.header] = col.field
+		result = append(result, col)
+	}
+	return result, nil
+}
+
+// findColumn looks up a column by its Alert field or header.
+func findColumn(name string) (column, bool) {
+	for _, col := range columns {
+		if strings.EqualFold(name, col.field) || strings.EqualFold(name, col.header) {
+			return col, true
+		}
+	}
+	return column{}, false
+}
+
+// columnFields returns the Alert field of every column.
+func columnFields() []string {
+	fields := make([]string, 0, len(columns))
+	for _, col := range columns {
+		fields = append(fields, col.field)
+	}
+	return fields
+}
+
+// columnHeaders returns the headers of the given columns.
+func columnHeaders(selected []column) []string {
+	headers := make([]string, 0, len(selected))
+	for _, col := range selected {
+		headers = append(headers, col.header)
+	}
+	return headers
+}
+
+// alertRow converts an alert into a row of the output CSV.
+func alertRow(selected []column, alert codeql.Alert) []string {
+	row := make([]string, 0, len(selected))
+	for _, col := range selected {
+		row = append(row, col.value(alert))
+	}
+	return row
+}
@@ -0,0 +1,268 @@
+package report
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+)
+
+// Kinds of change between two reports.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Diff is the difference between an old and a new report. Alerts are matched
+// by owner, repository and alert number, with owner and repository names
+// compared case-insensitively. Each list is sorted by repository and alert
+// number.
+type Diff struct {
+	OldAlerts int `json:"old_alerts"`
+	NewAlerts int `json:"new_alerts"`
+	Unchanged int `json:"unchanged"`
+
+	Added   []DiffEntry `json:"added"`
+	Removed []DiffEntry `json:"removed"`
+	Changed []DiffEntry `json:"changed"`
+}
+
+// DiffEntry is an alert that was added, removed or changed.
+type DiffEntry struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	ID    int    `json:"alert_id"`
+	// Fields are the alert's values in the new report, or in the old report
+	// if it was removed, keyed by Alert field name.
+	Fields map[string]string `json:"fields"`
+	// Changes lists the fields whose values differ, for changed alerts.
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is a field of an alert whose value differs between reports.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// reportRow is an alert read from a report, as the formatted value of each
+// field the report has.
+type reportRow map[string]string
+
+// key identifies the alert of the row across reports.
+func (r reportRow) key() string {
+	return strings.ToLower(r["Owner"]) + "/" + strings.ToLower(r["Repo"]) + "#" + r["ID"]
+}
+
+// CompareReports compares the alerts of two CSV or JSON reports written by
+// Generate. JSON reports may be JSON arrays, JSON documents with metadata or
+// JSON Lines. Only the fields present in both reports are compared. Reports
+// listing an alert more than once, such as those with AllInstances, are
+// compared on the first row of each alert.
+func CompareReports(oldPath, newPath string, opts csvpkg.Options) (*Diff, error) {
+	oldRows, oldFields, err := readReport(oldPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	newRows, newFields, err := readReport(newPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, field := range oldFields {
+		if slices.Contains(newFields, field) && field != "Owner" && field != "Repo" && field != "ID" {
+			fields = append(fields, field)
+		}
+	}
+
+	oldByKey := indexRows(oldRows)
+	newByKey := indexRows(newRows)
+	diff := &Diff{OldAlerts: len(oldByKey), NewAlerts: len(newByKey)}
+
+	for _, row := range uniqueRows(newRows) {
+		old, ok := oldByKey[row.key()]
+		if !ok {
+			diff.Added = append(diff.Added, newDiffEntry(row))
+			continue
+		}
+
+		var changes []FieldChange
+		for _, field := range fields {
+			if old[field] != row[field] {
+				changes = append(changes, FieldChange{Field: field, Old: old[field], New: row[field]})
+			}
+		}
+		if len(changes) == 0 {
+			diff.Unchanged++
+			continue
+		}
+		entry := newDiffEntry(row)
+		entry.Changes = changes
+		diff.Changed = append(diff.Changed, entry)
+	}
+
+	for _, row := range uniqueRows(oldRows) {
+		if _, ok := newByKey[row.key()]; !ok {
+			diff.Removed = append(diff.Removed, newDiffEntry(row))
+		}
+	}
+
+	for _, entries := range [][]DiffEntry{diff.Added, diff.Removed, diff.Changed} {
+		slices.SortFunc(entries, compareDiffEntries)
+	}
+	return diff, nil
+}
+
+// newDiffEntry returns the entry of the alert in row.
+func newDiffEntry(row reportRow) DiffEntry {
+	id, _ := strconv.Atoi(row["ID"])
+	return DiffEntry{Owner: row["Owner"], Repo: row["Repo"], ID: id, Fields: row}
+}
+
+// compareDiffEntries orders entries by repository, then by alert number.
+func compareDiffEntries(a, b DiffEntry) int {
+	return cmp.Or(
+		strings.Compare(strings.ToLower(a.Owner), strings.ToLower(b.Owner)),
+		strings.Compare(strings.ToLower(a.Repo), strings.ToLower(b.Repo)),
+		cmp.Compare(a.ID, b.ID),
+	)
+}
+
+// indexRows returns the first row of each alert by its key.
+func indexRows(rows []reportRow) map[string]reportRow {
+	byKey := make(map[string]reportRow, len(rows))
+	for _, row := range rows {
+		if _, ok := byKey[row.key()]; !ok {
+			byKey[row.key()] = row
+		}
+	}
+	return byKey
+}
+
+// uniqueRows returns the first row of each alert, in report order.
+func uniqueRows(rows []reportRow) []reportRow {
+	seen := make(map[string]bool, len(rows))
+	unique := make([]reportRow, 0, len(rows))
+	for _, row := range rows {
+		if !seen[row.key()] {
+			seen[row.key()] = true
+			unique = append(unique, row)
+		}
+	}
+	return unique
+}
+
+// readReport reads the rows of a CSV or JSON report and the Alert fields it
+// has. A JSON report is recognized by its first character.
+func readReport(path string, opts csvpkg.Options) ([]reportRow, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read report %s: %w", path, err)
+	}
+
+	var rows []reportRow
+	var fields []string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		rows, fields, err = readJSONReport(trimmed)
+	} else {
+		rows, fields, err = readCSVReport(path, opts)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read report %s: %w", path, err)
+	}
+
+	for _, field := range []string{"Owner", "Repo", "ID"} {
+		if !slices.Contains(fields, field) {
+			col, _ := findColumn(field)
+			return nil, nil, fmt.Errorf("report %s has no %q column, which is needed to match alerts", path, col.header)
+		}
+	}
+	return rows, fields, nil
+}
+
+// readCSVReport reads the rows of a CSV report, matching its headers to the
+// report columns. Columns that are not report columns are ignored.
+func readCSVReport(path string, opts csvpkg.Options) ([]reportRow, []string, error) {
+	opts.Append = false
+	reader := csvpkg.NewReader(path, opts)
+	records, err := reader.ReadAllWithHeaders()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := make(map[string]string)
+	var fields []string
+	for _, header := range reader.Headers() {
+		if col, ok := findColumn(header); ok && !slices.Contains(fields, col.field) {
+			headers[header] = col.field
+			fields = append(fields, col.field)
+		}
+	}
+
+	rows := make([]reportRow, 0, len(records))
+	for _, record := range records {
+		row := make(reportRow, len(fields))
+		for header, field := range headers {
+			row[field] = record[header]
+		}
+		rows = append(rows, row)
+	}
+	return rows, fields, nil
+}
+
+// readJSONReport reads the alerts of a JSON array, a JSON document with
+// metadata, or JSON Lines, and formats their fields as in a CSV report.
+func readJSONReport(data []byte) ([]reportRow, []string, error) {
+	var alerts []codeql.Alert
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &alerts); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode JSON: %w", err)
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var value json.RawMessage
+			if err := decoder.Decode(&value); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, nil, fmt.Errorf("failed to decode JSON: %w", err)
+			}
+
+			// A document with metadata holds every alert
+			var document jsonDocument
+			if err := json.Unmarshal(value, &document); err == nil && document.Metadata != nil {
+				alerts = append(alerts, document.Alerts...)
+				continue
+			}
+
+			var alert codeql.Alert
+			if err := json.Unmarshal(value, &alert); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode JSON: %w", err)
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+
+	fields := columnFields()
+	rows := make([]reportRow, 0, len(alerts))
+	for _, alert := range alerts {
+		row := make(reportRow, len(columns))
+		for _, col := range columns {
+			row[col.field] = col.value(alert)
+		}
+		rows = append(rows, row)
+	}
+	return rows, fields, nil
+}
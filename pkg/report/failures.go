@@ -0,0 +1,74 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+	jsonpkg "github.com/lindluni/gh-generate-codeql-report/pkg/json"
+)
+
+// Failure describes an input record or alert that could not be processed.
+type Failure struct {
+	Repository  string `json:"repository"`
+	AlertNumber string `json:"alert_number"`
+	Source      string `json:"source,omitempty"`
+	Error       string `json:"error"`
+}
+
+// newFailedRef describes an alert that could not be fetched.
+func newFailedRef(ref AlertRef, err error) Failure {
+	return Failure{
+		Repository:  ref.Owner + "/" + ref.Repo,
+		AlertNumber: strconv.FormatInt(ref.Number, 10),
+		Source:      ref.Source,
+		Error:       err.Error(),
+	}
+}
+
+// newFailedInput describes an input record that could not be parsed.
+func (c Config) newFailedInput(record Record, err error) Failure {
+	failure := Failure{
+		Repository:  record.Fields[c.RepoColumn],
+		AlertNumber: record.Fields[c.AlertColumn],
+		Source:      record.Source,
+		Error:       err.Error(),
+	}
+	if c.URLColumn != "" {
+		failure.Repository = record.Fields[c.URLColumn]
+		failure.AlertNumber = ""
+	}
+	return failure
+}
+
+// writeFailures writes the failed records to path, as JSON if it has a .json
+// extension and otherwise as a CSV that can be used as input to retry them.
+func (g *generator) writeFailures(path string, failures []Failure) error {
+	if err := g.createParentDir(path); err != nil {
+		return fmt.Errorf("failed to write error output: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if failures == nil {
+			failures = []Failure{}
+		}
+		if err := jsonpkg.NewWriter(path).WriteAll(failures); err != nil {
+			return fmt.Errorf("failed to write error output: %w", err)
+		}
+		return nil
+	}
+
+	// Use the input column names so the file can be read back as input
+	headers := []string{g.cfg.RepoColumn, g.cfg.AlertColumn, "Source File", "Error"}
+	rows := make([][]string, 0, len(failures))
+	for _, failure := range failures {
+		rows = append(rows, []string{failure.Repository, failure.AlertNumber, failure.Source, failure.Error})
+	}
+
+	if err := csvpkg.NewWriter(path, headers, g.cfg.csvOptions()).WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write error output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// fetchResult is the outcome of fetching the alert at index in the input.
+// With AllInstances it holds one alert per instance.
+type fetchResult struct {
+	index  int
+	alerts []codeql.Alert
+	// filtered counts the alerts excluded by a filter.
+	filtered filterCounts
+	err      error
+	// duration is how long the fetch took, not counting the wait for the limiter.
+	duration time.Duration
+}
+
+// fetchAlerts fetches the alerts for refs using up to MaxWorkers goroutines,
+// with the limiter deciding how many run at once. Results are sent in the order
+// they complete; the channel is closed once every fetch is done or ctx is done.
+// With GroupByRepo, the alerts of a repository are only fetched once those of
+// the previous repository are done, so their log lines are not interleaved.
+func (g *generator) fetchAlerts(ctx context.Context, limiter *codeql.Limiter, refs []AlertRef) <-chan fetchResult {
+	jobs := make(chan int)
+	results := make(chan fetchResult)
+
+	// Count the alerts of each repository for the headers of GroupByRepo, and
+	// track the fetches of the current repository
+	var repoAlerts map[string]int
+	var group *sync.WaitGroup
+	if g.cfg.GroupByRepo {
+		repoAlerts = make(map[string]int)
+		for _, ref := range refs {
+			repoAlerts[ref.repoKey()]++
+		}
+		group = &sync.WaitGroup{}
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range refs {
+			if ref := refs[i]; group != nil && (i == 0 || ref.repoKey() != refs[i-1].repoKey()) {
+				group.Wait()
+				g.logger.Info("Processing repo", slog.String("repo", ref.Owner+"/"+ref.Repo), slog.Int("alerts", repoAlerts[ref.repoKey()]))
+			}
+
+			if group != nil {
+				group.Add(1)
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				if group != nil {
+					group.Done()
+				}
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range min(g.cfg.MaxWorkers, len(refs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := fetchResult{index: i}
+				if result.err = limiter.Acquire(ctx); result.err == nil {
+					start := time.Now()
+					result.alerts, result.filtered, result.err = g.fetchAlert(ctx, refs[i])
+					result.duration = time.Since(start)
+					limiter.Release()
+
+					if g.cfg.Verbose {
+						ref := refs[i]
+						g.logger.Info("Fetched alert",
+							append(codeql.AlertAttrs(ref.Owner, ref.Repo, ref.Number), slog.Int64("duration_ms", result.duration.Milliseconds()))...)
+					}
+				}
+				if group != nil {
+					group.Done()
+				}
+
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// fetchAlert fetches the alert referenced by ref, with the configured
+// instances. Snippets and instance counts are only added to the alerts that
+// pass the filters.
+func (g *generator) fetchAlert(ctx context.Context, ref AlertRef) ([]codeql.Alert, filterCounts, error) {
+	alert, err := g.client.GetAlertAtRef(ctx, ref.Owner, ref.Repo, ref.Number, g.cfg.Ref)
+	if err != nil {
+		return nil, filterCounts{}, err
+	}
+
+	if g.cfg.WithSource {
+		alert.SourceFile = ref.Source
+	}
+
+	alerts, err := g.selectInstances(ctx, *alert)
+	if err != nil {
+		return nil, filterCounts{}, err
+	}
+	alerts, filtered := g.filterAlerts(alerts)
+	g.addSnippets(ctx, alerts)
+	g.addInstanceCounts(ctx, alerts)
+	return alerts, filtered, nil
+}
+
+// Instances that can be selected for Config.Instance.
+const (
+	// InstanceMostRecent reports the location of the most recent instance,
+	// as returned with the alert.
+	InstanceMostRecent = "most-recent"
+	// InstanceFirst reports the location of the first instance listed for
+	// the alert, which takes another request per alert.
+	InstanceFirst = "first"
+)
+
+// Instances lists the instances that can be selected for Config.Instance.
+var Instances = []string{InstanceMostRecent, InstanceFirst}
+
+// selectInstances returns the alert as it is reported: one copy per instance
+// with AllInstances, or with the location of the selected instance.
+func (g *generator) selectInstances(ctx context.Context, alert codeql.Alert) ([]codeql.Alert, error) {
+	switch {
+	case g.cfg.AllInstances:
+		return g.expandInstances(ctx, alert)
+	case g.cfg.Instance == InstanceFirst && g.cfg.Ref == "":
+		first, err := g.firstInstance(ctx, alert)
+		if err != nil {
+			return nil, err
+		}
+		return []codeql.Alert{first}, nil
+	default:
+		return []codeql.Alert{alert}, nil
+	}
+}
+
+// firstInstance returns the alert with the location of its first listed
+// instance, or the alert itself if it has none.
+func (g *generator) firstInstance(ctx context.Context, alert codeql.Alert) (codeql.Alert, error) {
+	instances, err := g.client.GetAlertInstances(ctx, alert.Owner, alert.Repo, int64(alert.ID), "")
+	if err != nil {
+		return codeql.Alert{}, fmt.Errorf("failed to get instances of alert #%d for %s/%s: %w", alert.ID, alert.Owner, alert.Repo, err)
+	}
+	if len(instances) == 0 {
+		return alert, nil
+	}
+	return alert.WithInstance(instances[0]), nil
+}
+
+// addSnippets sets the snippet of each alert with WithSnippet. An alert whose
+// snippet cannot be fetched is still reported, without a snippet.
+func (g *generator) addSnippets(ctx context.Context, alerts []codeql.Alert) {
+	if !g.cfg.WithSnippet {
+		return
+	}
+
+	for i := range alerts {
+		alert := &alerts[i]
+		snippet, err := g.client.GetSnippet(ctx, *alert)
+		if err != nil {
+			g.logger.Error("Failed to get alert snippet",
+				append(codeql.AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID)), slog.Any("error", err))...)
+			continue
+		}
+		alert.Snippet = snippet
+	}
+}
+
+// addInstanceCounts sets the instance count of each alert with
+// WithInstanceCount. An alert whose instances cannot be listed is still
+// reported, without a count.
+func (g *generator) addInstanceCounts(ctx context.Context, alerts []codeql.Alert) {
+	if !g.cfg.WithInstanceCount {
+		return
+	}
+
+	for i := range alerts {
+		alert := &alerts[i]
+		count, err := g.client.CountAlertInstances(ctx, alert.Owner, alert.Repo, int64(alert.ID))
+		if err != nil {
+			g.logger.Error("Failed to count alert instances",
+				append(codeql.AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID)), slog.Any("error", err))...)
+			continue
+		}
+		alert.InstanceCount = count
+	}
+}
+
+// expandInstances returns a copy of the alert for each of its instances, or
+// the alert itself if it has none.
+func (g *generator) expandInstances(ctx context.Context, alert codeql.Alert) ([]codeql.Alert, error) {
+	instances, err := g.client.GetAlertInstances(ctx, alert.Owner, alert.Repo, int64(alert.ID), g.cfg.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instances of alert #%d for %s/%s: %w", alert.ID, alert.Owner, alert.Repo, err)
+	}
+	if len(instances) == 0 {
+		return []codeql.Alert{alert}, nil
+	}
+
+	alerts := make([]codeql.Alert, 0, len(instances))
+	for _, instance := range instances {
+		alerts = append(alerts, alert.WithInstance(instance))
+	}
+	return alerts, nil
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// filterCounts counts the alerts excluded by a filter.
+type filterCounts struct {
+	// excluded is the number of alerts excluded by any filter, of which
+	// beforeSince were created before Config.Since.
+	excluded    int
+	beforeSince int
+}
+
+// filterAlerts returns the alerts that pass the configured filters and counts
+// the ones it excludes. Alerts are filtered as soon as they are fetched or
+// listed, so no further requests are made for the excluded ones.
+func (g *generator) filterAlerts(alerts []codeql.Alert) ([]codeql.Alert, filterCounts) {
+	var counts filterCounts
+	kept := make([]codeql.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		switch {
+		case g.createdBeforeSince(alert):
+			counts.excluded++
+			counts.beforeSince++
+		case !g.keepAlert(alert):
+			counts.excluded++
+		default:
+			kept = append(kept, alert)
+		}
+	}
+	return kept, counts
+}
+
+// emitAlert writes an alert that passed the filters to the report.
+func (g *generator) emitAlert(writer reportWriter, alert codeql.Alert) error {
+	if g.cfg.WithHash {
+		alert.Hash = codeql.AlertHash(alert)
+	}
+	if !g.cfg.WithRuleFields {
+		alert.RuleSeverity, alert.Help = "", ""
+	}
+
+	if err := writer.WriteAlert(alert); err != nil {
+		return err
+	}
+	g.result.addAlert(alert)
+	return nil
+}
+
+// keepAlert reports whether an alert passes the configured filters.
+func (g *generator) keepAlert(alert codeql.Alert) bool {
+	cfg := g.cfg
+
+	if cfg.Tool != "" && !strings.EqualFold(alert.Tool, cfg.Tool) {
+		if cfg.Verbose {
+			g.logger.Info("Excluding alert reported by another tool",
+				append(codeql.AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID)), slog.String("tool", alert.Tool), slog.String("want_tool", cfg.Tool))...)
+		}
+		return false
+	}
+
+	if len(cfg.PathFilters) > 0 && !g.matchesPathFilter(alert.FilePath) {
+		if cfg.Verbose {
+			g.logger.Info("Excluding alert matching no path filter",
+				append(codeql.AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID)), slog.String("path", alert.FilePath))...)
+		}
+		return false
+	}
+
+	if cfg.MinSeverity != "" {
+		rank := codeql.SeverityRank(alert.Severity)
+		if rank == 0 {
+			if !cfg.IncludeUnranked {
+				if cfg.Verbose {
+					g.logger.Info("Excluding alert without a security severity", codeql.AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID))...)
+				}
+				return false
+			}
+		} else if rank < codeql.SeverityRank(cfg.MinSeverity) {
+			if cfg.Verbose {
+				g.logger.Info("Excluding alert below the minimum severity",
+					append(codeql.AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID)), slog.String("severity", alert.Severity), slog.String("min_severity", cfg.MinSeverity))...)
+			}
+			return false
+		}
+	}
+
+	return true
+}
+
+// createdBeforeSince reports whether an alert was created before cfg.Since.
+func (g *generator) createdBeforeSince(alert codeql.Alert) bool {
+	since := g.cfg.Since
+	if since.IsZero() || !alert.CreatedAt.Before(since) {
+		return false
+	}
+	if g.cfg.Verbose {
+		g.logger.Info("Excluding alert created before the since time",
+			append(codeql.AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID)), slog.String("created_at", FormatTime(alert.CreatedAt)), slog.String("since", FormatTime(since)))...)
+	}
+	return true
+}
+
+// matchesPathFilter reports whether filePath matches any of the path filter globs.
+func (g *generator) matchesPathFilter(filePath string) bool {
+	for _, pattern := range g.cfg.PathFilters {
+		if doublestar.MatchUnvalidated(pattern, filePath) {
+			return true
+		}
+	}
+	return false
+}
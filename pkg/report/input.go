@@ -0,0 +1,368 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+)
+
+// Record is a single data row read from an input file.
+type Record struct {
+	Source string
+	Index  int
+	Fields map[string]string
+}
+
+// String describes where the record was read from, for log messages.
+func (r Record) String() string {
+	return fmt.Sprintf("record %d of %s", r.Index, r.Source)
+}
+
+// AlertRef identifies a single alert referenced by the input.
+type AlertRef struct {
+	Owner  string
+	Repo   string
+	Number int64
+
+	// Source is the input file the alert was listed in.
+	Source string
+}
+
+// key returns a copy of the reference suitable for comparing alerts. Owner and
+// repository names are compared case-insensitively, as on GitHub.
+func (r AlertRef) key() AlertRef {
+	return AlertRef{
+		Owner:  strings.ToLower(r.Owner),
+		Repo:   strings.ToLower(r.Repo),
+		Number: r.Number,
+	}
+}
+
+// String formats the reference as owner/repo#number.
+func (r AlertRef) String() string {
+	return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number)
+}
+
+// ErrNoRecords is returned by ReadRecords with FailOnEmpty when the input files
+// contain no data rows.
+var ErrNoRecords = errors.New("input contained no data rows")
+
+// csvOptions returns the options for reading and writing CSV files.
+func (c Config) csvOptions() csvpkg.Options {
+	opts := c.CSV
+	opts.Append = false
+	return opts
+}
+
+// ReadRecords reads every input file of cfg and checks that the configured
+// columns exist. It also returns the number of malformed rows skipped because
+// of csv.Options.SkipBadRows. Only the first cfg.Limit records are returned
+// if it is set. An input file named csv.StdinPath is read from standard input.
+// With cfg.URLList, the files list alert URLs instead of CSV rows.
+// Header columns other than the configured ones are logged once, since they
+// are not copied to the report. An input without data rows is logged as a
+// warning, or with cfg.FailOnEmpty fails with ErrNoRecords. A file without a
+// header row fails with csv.ErrEmptyFile.
+func ReadRecords(cfg Config) ([]Record, int, error) {
+	cfg = cfg.withDefaults()
+	logger := cfg.Logger
+
+	var records []Record
+	var skipped int
+	var unused []string
+
+	for _, inputFile := range cfg.InputFiles {
+		source := inputFile
+		if inputFile == csvpkg.StdinPath {
+			source = "stdin"
+		}
+		logger.Info("Reading input", slog.String("source", source))
+
+		var rows []map[string]string
+		var err error
+		if cfg.URLList {
+			rows, err = readURLList(inputFile, source)
+		} else {
+			var headers []string
+			var n int
+			rows, headers, n, err = readInputCSV(cfg, inputFile, source)
+			skipped += n
+			for _, header := range headers {
+				if header != "" && !slices.Contains(cfg.inputColumns(), header) && !slices.Contains(unused, header) {
+					unused = append(unused, header)
+				}
+			}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(rows) == 0 && len(cfg.InputFiles) > 1 {
+			logger.Warn("Input contained no data rows", slog.String("source", source))
+		}
+		for i, row := range rows {
+			records = append(records, Record{Source: source, Index: i + 1, Fields: row})
+		}
+	}
+
+	logger.Info("Found records to process", slog.Int("records", len(records)))
+	if len(unused) > 0 {
+		// Only the alert columns are read, the report is built from the API
+		logger.Info("Ignoring input columns not used to find alerts", slog.String("columns", strings.Join(unused, ", ")))
+	}
+	if skipped > 0 {
+		logger.Info("Skipped malformed rows", slog.Int("rows", skipped))
+	}
+
+	if len(records) == 0 {
+		if cfg.FailOnEmpty {
+			return nil, 0, ErrNoRecords
+		}
+		message := "Input contained no data rows"
+		logger.Warn(message)
+		fmt.Fprintf(cfg.Messages, "%s\n", message)
+	}
+
+	if cfg.Limit > 0 && len(records) > cfg.Limit {
+		logger.Warn("Limiting input records", slog.Int("limit", cfg.Limit), slog.Int("records", len(records)))
+		fmt.Fprintf(cfg.Messages, "Limiting input to the first %d of %d records, %d records are not processed\n",
+			cfg.Limit, len(records), len(records)-cfg.Limit)
+		records = records[:cfg.Limit]
+	}
+	return records, skipped, nil
+}
+
+// readInputCSV reads the rows of an input CSV file and checks that the
+// configured columns exist. It also returns the file's headers and the number
+// of malformed rows skipped.
+func readInputCSV(cfg Config, inputFile, source string) ([]map[string]string, []string, int, error) {
+	csvReader := csvpkg.NewReader(inputFile, cfg.csvOptions())
+	rows, err := csvReader.ReadAllWithHeaders()
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to read input CSV %s: %w", source, err)
+	}
+
+	for _, err := range csvReader.SkippedRows() {
+		cfg.Logger.Info("Skipping malformed row", slog.String("source", source), slog.Any("error", err))
+	}
+
+	// Make sure the configured columns exist so the files can be merged
+	for _, column := range cfg.inputColumns() {
+		if !slices.Contains(csvReader.Headers(), column) {
+			return nil, nil, 0, fmt.Errorf("input CSV %s is missing column %q", source, column)
+		}
+	}
+	return rows, csvReader.Headers(), len(csvReader.SkippedRows()), nil
+}
+
+// URLListColumn is the column the alert URLs of a URL list are read into.
+const URLListColumn = "Alert URL"
+
+// readURLList reads an input file listing alert URLs, one per line, as rows
+// with a URLListColumn. Blank lines are ignored.
+func readURLList(inputFile, source string) ([]map[string]string, error) {
+	var data []byte
+	var err error
+	if inputFile == csvpkg.StdinPath {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(inputFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert URLs from %s: %w", source, err)
+	}
+
+	var rows []map[string]string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rows = append(rows, map[string]string{URLListColumn: line})
+		}
+	}
+	return rows, nil
+}
+
+// inputColumns returns the columns every input file must contain.
+func (c Config) inputColumns() []string {
+	if c.URLColumn != "" {
+		return []string{c.URLColumn}
+	}
+	return []string{c.RepoColumn, c.AlertColumn}
+}
+
+// alertURLPattern matches the web URL of a code scanning alert, e.g.
+// https://github.com/octo-org/octo-repo/security/code-scanning/42
+var alertURLPattern = regexp.MustCompile(`^https?://[^/]+/([^/]+)/([^/]+)/security/code-scanning/(\d+)/?(?:[?#].*)?$`)
+
+// parseAlertURL extracts the repository and alert number from an alert's web URL.
+func parseAlertURL(alertURL string) (owner, repo string, number int64, err error) {
+	match := alertURLPattern.FindStringSubmatch(strings.TrimSpace(alertURL))
+	if match == nil {
+		return "", "", 0, fmt.Errorf("invalid alert URL %q: expected https://<host>/<owner>/<repo>/security/code-scanning/<number>", alertURL)
+	}
+
+	number, err = strconv.ParseInt(match[3], 10, 64)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to parse alert number in URL %q: %w", alertURL, err)
+	}
+	if number < 1 {
+		return "", "", 0, fmt.Errorf("invalid alert number in URL %q: must be a positive integer", alertURL)
+	}
+	if err := ValidateRepository(match[1], match[2]); err != nil {
+		return "", "", 0, err
+	}
+	return match[1], match[2], number, nil
+}
+
+// ParseRecord extracts the repository and alert number from an input record,
+// either from the URL column or the repository and alert columns of cfg.
+func ParseRecord(cfg Config, record Record) (AlertRef, error) {
+	cfg = cfg.withDefaults()
+
+	if cfg.URLColumn != "" {
+		owner, repo, number, err := parseAlertURL(record.Fields[cfg.URLColumn])
+		if err != nil {
+			return AlertRef{}, err
+		}
+		return AlertRef{Owner: owner, Repo: repo, Number: number, Source: record.Source}, nil
+	}
+
+	// Extract repository owner and name
+	owner, repo, err := SplitRepository(record.Fields[cfg.RepoColumn])
+	if err != nil {
+		return AlertRef{}, err
+	}
+
+	// Parse alert number
+	alertNumber := record.Fields[cfg.AlertColumn]
+	number, err := strconv.ParseInt(alertNumber, 10, 64)
+	if err != nil {
+		return AlertRef{}, fmt.Errorf("failed to parse alert number '%s': %w", alertNumber, err)
+	}
+
+	// Alert numbers start at 1, so anything else would only fail on the API
+	if number < 1 {
+		return AlertRef{}, fmt.Errorf("invalid alert number '%s': must be a positive integer", alertNumber)
+	}
+
+	return AlertRef{Owner: owner, Repo: repo, Number: number, Source: record.Source}, nil
+}
+
+// ParseRecords parses every input record, logging and returning those that are invalid.
+func ParseRecords(cfg Config, records []Record) ([]AlertRef, []Failure) {
+	cfg = cfg.withDefaults()
+	refs := make([]AlertRef, 0, len(records))
+	var invalid []Failure
+
+	for _, record := range records {
+		ref, err := ParseRecord(cfg, record)
+		if err != nil {
+			cfg.Logger.Info("Skipping invalid record", slog.String("record", record.String()), slog.Any("error", err))
+			invalid = append(invalid, cfg.newFailedInput(record, err))
+			continue
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, invalid
+}
+
+// DedupeRefs removes repeated references to the same alert, keeping the first.
+func DedupeRefs(refs []AlertRef, logger *slog.Logger) []AlertRef {
+	seen := make(map[AlertRef]bool, len(refs))
+	unique := refs[:0]
+
+	for _, ref := range refs {
+		key := ref.key()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, ref)
+	}
+
+	if duplicates := len(refs) - len(unique); duplicates > 0 && logger != nil {
+		logger.Info("Collapsed duplicate alert references", slog.Int("duplicates", duplicates))
+	}
+	return unique
+}
+
+// GroupRefsByRepo sorts refs so the alerts of each repository are next to each
+// other, with the repositories in case-insensitive order of their owner/name
+// and the alerts of a repository in input order.
+func GroupRefsByRepo(refs []AlertRef) []AlertRef {
+	slices.SortStableFunc(refs, func(a, b AlertRef) int {
+		return strings.Compare(a.repoKey(), b.repoKey())
+	})
+	return refs
+}
+
+// repoKey returns the owner/repo of the reference in lower case, as owner and
+// repository names are compared case-insensitively on GitHub.
+func (r AlertRef) repoKey() string {
+	return strings.ToLower(r.Owner + "/" + r.Repo)
+}
+
+// ReadReposFile reads the owner/name repositories listed in a file, one per
+// line. Blank lines and comments starting with # are ignored.
+func ReadReposFile(path string, logger *slog.Logger) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repositories file %s: %w", path, err)
+	}
+
+	var repos []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		line = strings.TrimSpace(line)
+		if line != "" {
+			repos = append(repos, line)
+		}
+	}
+
+	if logger != nil {
+		logger.Info("Found repositories", slog.Int("repositories", len(repos)), slog.String("file", path))
+	}
+	return repos, nil
+}
+
+// SplitRepository splits an owner/name string into its owner and name.
+// Surrounding whitespace is ignored.
+func SplitRepository(fullName string) (string, string, error) {
+	parts := strings.Split(strings.TrimSpace(fullName), "/")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid repository format: %s", fullName)
+	}
+	if err := ValidateRepository(parts[0], parts[1]); err != nil {
+		return "", "", err
+	}
+	return parts[0], parts[1], nil
+}
+
+// ownerPattern matches GitHub user and organization names.
+var ownerPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// repoNamePattern matches GitHub repository names.
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidateRepository checks that owner and repo are non-empty and only use
+// the characters GitHub allows, so invalid names fail before any API call.
+func ValidateRepository(owner, repo string) error {
+	if owner == "" || repo == "" {
+		return fmt.Errorf("invalid repository %q: owner and name must not be empty", owner+"/"+repo)
+	}
+	if !ownerPattern.MatchString(owner) {
+		return fmt.Errorf("invalid repository owner %q: must start with a letter or digit and contain only letters, digits, hyphens and underscores", owner)
+	}
+	if !repoNamePattern.MatchString(repo) || repo == "." || repo == ".." {
+		return fmt.Errorf("invalid repository name %q: must contain only letters, digits, hyphens, underscores and periods", repo)
+	}
+	return nil
+}
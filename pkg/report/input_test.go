@@ -0,0 +1,94 @@
+package report
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitRepository(t *testing.T) {
+	tests := []struct {
+		fullName  string
+		wantOwner string
+		wantRepo  string
+		wantErr   bool
+	}{
+		{fullName: "org/repo", wantOwner: "org", wantRepo: "repo"},
+		{fullName: " org/repo ", wantOwner: "org", wantRepo: "repo"},
+		{fullName: "my-org/my.repo_1", wantOwner: "my-org", wantRepo: "my.repo_1"},
+		{fullName: "/repo", wantErr: true},
+		{fullName: "org/", wantErr: true},
+		{fullName: "org/repo/", wantErr: true},
+		{fullName: "org/re po", wantErr: true},
+		{fullName: "org", wantErr: true},
+		{fullName: "org/..", wantErr: true},
+		{fullName: "-org/repo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fullName, func(t *testing.T) {
+			owner, repo, err := SplitRepository(tt.fullName)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("SplitRepository(%q) = %q, %q, want an error", tt.fullName, owner, repo)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitRepository(%q) error = %v", tt.fullName, err)
+			}
+			if owner != tt.wantOwner || repo != tt.wantRepo {
+				t.Errorf("SplitRepository(%q) = %q, %q, want %q, %q", tt.fullName, owner, repo, tt.wantOwner, tt.wantRepo)
+			}
+		})
+	}
+}
+
+func TestValidateRepository(t *testing.T) {
+	tests := []struct {
+		owner   string
+		repo    string
+		wantErr bool
+	}{
+		{owner: "org", repo: "repo"},
+		{owner: "", repo: "repo", wantErr: true},
+		{owner: "org", repo: "", wantErr: true},
+		{owner: "org", repo: "re po", wantErr: true},
+		{owner: "o rg", repo: "repo", wantErr: true},
+		{owner: "org", repo: "repo/", wantErr: true},
+		{owner: "org", repo: ".", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.owner+"/"+tt.repo, func(t *testing.T) {
+			err := ValidateRepository(tt.owner, tt.repo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRepository(%q, %q) error = %v, want error %v", tt.owner, tt.repo, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRecordRejectsInvalidAlertNumbers(t *testing.T) {
+	for _, number := range []string{"0", "-5", "3.14"} {
+		t.Run(number, func(t *testing.T) {
+			record := Record{Fields: map[string]string{"Repository": "org/repo", "Alert Number": number}}
+			if ref, err := ParseRecord(Config{}, record); err == nil {
+				t.Fatalf("ParseRecord() = %v, want an error", ref)
+			}
+
+			server := newFakeGitHub(t, nil)
+			output := filepath.Join(t.TempDir(), "report.csv")
+			result, err := Generate(context.Background(), testConfig(server, writeInput(t, number), output))
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if result.Failed != 1 || result.Written != 0 {
+				t.Errorf("Failed = %d, Written = %d, want 1 and 0", result.Failed, result.Written)
+			}
+			if requests := server.alertRequests.Load(); requests != 0 {
+				t.Errorf("made %d alert requests, want none", requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"time"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// Metadata describes a report. It is written before the alerts by the formats
+// that support it when Config.Title is set.
+type Metadata struct {
+	Title       string    `json:"title"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Version     string    `json:"version,omitempty"`
+	Command     string    `json:"command,omitempty"`
+	TotalAlerts int       `json:"total_alerts"`
+}
+
+// metadata returns the metadata of the configured report, without the time
+// and alert count of a particular rendering, or nil if Title is not set.
+func (c Config) metadata() *Metadata {
+	if c.Title == "" {
+		return nil
+	}
+	return &Metadata{Title: c.Title, Version: c.Version, Command: c.Command}
+}
+
+// forAlerts returns a copy of m for a report of alerts generated now, or nil
+// if m is nil.
+func (m *Metadata) forAlerts(alerts []codeql.Alert) *Metadata {
+	if m == nil {
+		return nil
+	}
+	metadata := *m
+	metadata.GeneratedAt = time.Now().UTC().Truncate(time.Second)
+	metadata.TotalAlerts = len(alerts)
+	return &metadata
+}
+
+// jsonDocument is a JSON report with metadata, written instead of an array of
+// alerts when Title is set.
+type jsonDocument struct {
+	Metadata *Metadata      `json:"metadata"`
+	Alerts   []codeql.Alert `json:"alerts"`
+}
@@ -0,0 +1,356 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+	htmlpkg "github.com/lindluni/gh-generate-codeql-report/pkg/html"
+	jsonpkg "github.com/lindluni/gh-generate-codeql-report/pkg/json"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/markdown"
+	"github.com/lindluni/gh-generate-codeql-report/pkg/sarif"
+)
+
+// Built-in output formats.
+const (
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "markdown"
+	FormatSARIF    = "sarif"
+	FormatHTML     = "html"
+)
+
+func init() {
+	RegisterFormat(FormatCSV, func(cfg Config) (Renderer, error) {
+		columns, err := resolveColumns(&cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts := cfg.csvOptions()
+		opts.NoHeader = cfg.NoHeader
+		return &csvRenderer{columns: columns, opts: opts}, nil
+	})
+	RegisterFormat(FormatJSON, func(cfg Config) (Renderer, error) {
+		if metadata := cfg.metadata(); metadata != nil {
+			return jsonDocumentRenderer{metadata: metadata}, nil
+		}
+		return jsonRenderer{}, nil
+	})
+	RegisterFormat(FormatJSONL, func(Config) (Renderer, error) { return jsonLinesRenderer{}, nil })
+	RegisterFormat(FormatMarkdown, func(cfg Config) (Renderer, error) {
+		return markdownRenderer{metadata: cfg.metadata()}, nil
+	})
+	RegisterFormat(FormatSARIF, func(Config) (Renderer, error) { return sarifRenderer{}, nil })
+	RegisterFormat(FormatHTML, func(cfg Config) (Renderer, error) {
+		return htmlRenderer{metadata: cfg.metadata()}, nil
+	})
+}
+
+// formatExtensions lists the file extensions expected for each built-in
+// format, the first being the default.
+var formatExtensions = map[string][]string{
+	FormatCSV:      {".csv"},
+	FormatJSON:     {".json"},
+	FormatJSONL:    {".jsonl", ".ndjson"},
+	FormatMarkdown: {".md", ".markdown"},
+	FormatSARIF:    {".sarif", ".json"},
+	FormatHTML:     {".html", ".htm"},
+}
+
+// FormatExtensions returns the file extensions expected for a format, the
+// first being the default. Formats registered by other packages are expected
+// to use their name as the extension.
+func FormatExtensions(format string) []string {
+	if extensions, ok := formatExtensions[format]; ok {
+		return slices.Clone(extensions)
+	}
+	return []string{"." + format}
+}
+
+// StdoutPath is the output file path that writes the report to standard output.
+const StdoutPath = "-"
+
+// reportWriter writes alerts one at a time in a specific output format.
+type reportWriter interface {
+	WriteAlert(alert codeql.Alert) error
+	Close() error
+}
+
+// newReportWriter creates the output file and returns the reportWriter for the
+// configured format. Formats that can be streamed are written as alerts are
+// fetched, and if appendToFile is set, alerts are added to the end of an
+// existing file. Other formats, and reports split into several files, are
+// rendered once every alert is fetched.
+func (g *generator) newReportWriter(appendToFile bool) (reportWriter, error) {
+	renderer, err := newRenderer(g.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.cfg.SplitBy == SplitByRepo {
+		return newSplitReportWriter(g.cfg.OutputFile, g.cfg.Format, renderer, g.logger)
+	}
+
+	if g.cfg.OutputFile != StdoutPath {
+		if err := g.createParentDir(g.cfg.OutputFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if stream, ok := renderer.(streamRenderer); ok {
+		return stream.openStream(g.cfg.OutputFile, appendToFile)
+	}
+	return &bufferedReportWriter{filePath: g.cfg.OutputFile, renderer: renderer}, nil
+}
+
+// createParentDir creates the directory of filePath if it does not exist, so
+// writing the file cannot fail for a missing directory once alerts are fetched.
+func (g *generator) createParentDir(filePath string) error {
+	dir := filepath.Dir(filePath)
+	if _, err := os.Stat(dir); err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	g.logger.Warn("Output directory does not exist, creating it", slog.String("dir", dir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// csvRenderer renders alerts as CSV rows with the selected columns.
+type csvRenderer struct {
+	columns []column
+	opts    csvpkg.Options
+}
+
+// Write writes the header row and a row for each alert to out.
+func (r *csvRenderer) Write(alerts []codeql.Alert, out io.Writer) error {
+	rows := make([][]string, len(alerts))
+	for i, alert := range alerts {
+		rows[i] = alertRow(r.columns, alert)
+	}
+	return csvpkg.Encode(out, columnHeaders(r.columns), rows, r.opts)
+}
+
+// openStream creates the output CSV, or opens it for appending.
+func (r *csvRenderer) openStream(filePath string, appendToFile bool) (reportWriter, error) {
+	opts := r.opts
+	opts.Append = appendToFile
+	writer := csvpkg.NewWriter(filePath, columnHeaders(r.columns), opts)
+	if err := writer.Open(); err != nil {
+		return nil, fmt.Errorf("failed to write output CSV: %w", err)
+	}
+	return &csvReportWriter{writer: writer, columns: r.columns}, nil
+}
+
+// jsonRenderer renders alerts as a JSON array.
+type jsonRenderer struct{}
+
+// Write writes the alerts to out as an indented JSON array.
+func (jsonRenderer) Write(alerts []codeql.Alert, out io.Writer) error {
+	if alerts == nil {
+		alerts = []codeql.Alert{}
+	}
+	return jsonpkg.Encode(out, alerts)
+}
+
+// openStream creates the output JSON file.
+func (jsonRenderer) openStream(filePath string, _ bool) (reportWriter, error) {
+	writer := jsonpkg.NewWriter(filePath)
+	if err := writer.Open(); err != nil {
+		return nil, fmt.Errorf("failed to write output JSON: %w", err)
+	}
+	return &jsonReportWriter{writer: writer}, nil
+}
+
+// jsonDocumentRenderer renders alerts as a JSON object with the report's
+// metadata. It cannot be streamed, as the metadata includes the alert count.
+type jsonDocumentRenderer struct {
+	metadata *Metadata
+}
+
+// Write writes the metadata and the alerts to out as an indented JSON object.
+func (r jsonDocumentRenderer) Write(alerts []codeql.Alert, out io.Writer) error {
+	if alerts == nil {
+		alerts = []codeql.Alert{}
+	}
+	return jsonpkg.Encode(out, jsonDocument{Metadata: r.metadata.forAlerts(alerts), Alerts: alerts})
+}
+
+// jsonLinesRenderer renders alerts as JSON objects, one per line.
+type jsonLinesRenderer struct{}
+
+// Write writes each alert to out as a line of compact JSON.
+func (jsonLinesRenderer) Write(alerts []codeql.Alert, out io.Writer) error {
+	for _, alert := range alerts {
+		if err := jsonpkg.EncodeLine(out, alert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openStream creates the output JSON Lines file, or opens it for appending.
+func (jsonLinesRenderer) openStream(filePath string, appendToFile bool) (reportWriter, error) {
+	writer := jsonpkg.NewLinesWriter(filePath)
+	if err := writer.Open(appendToFile); err != nil {
+		return nil, fmt.Errorf("failed to write output JSON Lines: %w", err)
+	}
+	return &jsonLinesReportWriter{writer: writer}, nil
+}
+
+// markdownRenderer renders alerts as a Markdown summary, with a title block
+// if metadata is set.
+type markdownRenderer struct {
+	metadata *Metadata
+}
+
+// Write writes the Markdown summary of the alerts to out.
+func (r markdownRenderer) Write(alerts []codeql.Alert, out io.Writer) error {
+	writer := markdown.NewWriter(out)
+	if metadata := r.metadata.forAlerts(alerts); metadata != nil {
+		writer.SetHeader(markdown.Header{
+			Title:       metadata.Title,
+			GeneratedAt: metadata.GeneratedAt,
+			Version:     metadata.Version,
+			Command:     metadata.Command,
+		})
+	}
+	return writer.WriteAll(alerts)
+}
+
+// sarifRenderer renders alerts as a SARIF log.
+type sarifRenderer struct{}
+
+// Write writes the SARIF log of the alerts to out.
+func (sarifRenderer) Write(alerts []codeql.Alert, out io.Writer) error {
+	return sarif.NewWriter(out).WriteAll(alerts)
+}
+
+// htmlRenderer renders alerts as a self-contained HTML page, describing the
+// report at the top if metadata is set.
+type htmlRenderer struct {
+	metadata *Metadata
+}
+
+// Write writes the HTML page of the alerts to out.
+func (r htmlRenderer) Write(alerts []codeql.Alert, out io.Writer) error {
+	writer := htmlpkg.NewWriter(out)
+	if metadata := r.metadata.forAlerts(alerts); metadata != nil {
+		writer.SetHeader(htmlpkg.Header{
+			Title:       metadata.Title,
+			GeneratedAt: metadata.GeneratedAt,
+			Version:     metadata.Version,
+			Command:     metadata.Command,
+		})
+	}
+	return writer.WriteAll(alerts)
+}
+
+// csvReportWriter writes alerts as CSV rows.
+type csvReportWriter struct {
+	writer  *csvpkg.Writer
+	columns []column
+}
+
+// WriteAlert writes the alert as a row of the output CSV.
+func (w *csvReportWriter) WriteAlert(alert codeql.Alert) error {
+	if err := w.writer.Write(alertRow(w.columns, alert)); err != nil {
+		return fmt.Errorf("failed to write output CSV: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the output CSV.
+func (w *csvReportWriter) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("failed to write output CSV: %w", err)
+	}
+	return nil
+}
+
+// jsonReportWriter writes alerts as elements of a JSON array.
+type jsonReportWriter struct {
+	writer *jsonpkg.Writer
+}
+
+// WriteAlert appends the alert to the output JSON array.
+func (w *jsonReportWriter) WriteAlert(alert codeql.Alert) error {
+	if err := w.writer.Write(alert); err != nil {
+		return fmt.Errorf("failed to write output JSON: %w", err)
+	}
+	return nil
+}
+
+// Close ends the JSON array and closes the output file.
+func (w *jsonReportWriter) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("failed to write output JSON: %w", err)
+	}
+	return nil
+}
+
+// jsonLinesReportWriter writes alerts as JSON objects, one per line.
+type jsonLinesReportWriter struct {
+	writer *jsonpkg.LinesWriter
+}
+
+// WriteAlert writes the alert as the next line of the output file.
+func (w *jsonLinesReportWriter) WriteAlert(alert codeql.Alert) error {
+	if err := w.writer.Write(alert); err != nil {
+		return fmt.Errorf("failed to write output JSON Lines: %w", err)
+	}
+	return nil
+}
+
+// Close closes the output file.
+func (w *jsonLinesReportWriter) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("failed to write output JSON Lines: %w", err)
+	}
+	return nil
+}
+
+// bufferedReportWriter collects alerts and renders them all at once when closed,
+// for formats that cannot be written one alert at a time.
+type bufferedReportWriter struct {
+	filePath string
+	renderer Renderer
+	alerts   []codeql.Alert
+}
+
+// WriteAlert adds the alert to the report.
+func (w *bufferedReportWriter) WriteAlert(alert codeql.Alert) error {
+	w.alerts = append(w.alerts, alert)
+	return nil
+}
+
+// Close renders the collected alerts to the output file.
+func (w *bufferedReportWriter) Close() error {
+	if w.filePath == StdoutPath {
+		if err := w.renderer.Write(w.alerts, os.Stdout); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.Create(w.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", w.filePath, err)
+	}
+	defer f.Close()
+
+	if err := w.renderer.Write(w.alerts, f); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return f.Close()
+}
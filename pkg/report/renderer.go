@@ -0,0 +1,76 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// Renderer writes a complete report of alerts in one output format.
+type Renderer interface {
+	Write(alerts []codeql.Alert, out io.Writer) error
+}
+
+// RendererFactory creates the Renderer for a report generated with cfg.
+type RendererFactory func(cfg Config) (Renderer, error)
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = make(map[string]RendererFactory)
+)
+
+// RegisterFormat makes an output format available under name, for use as
+// Config.Format. The built-in formats register themselves when the package is
+// loaded. It panics if factory is nil or name is already registered.
+func RegisterFormat(name string, factory RendererFactory) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+
+	if factory == nil {
+		panic("report: RegisterFormat factory is nil")
+	}
+	if _, ok := renderers[name]; ok {
+		panic("report: RegisterFormat called twice for format " + name)
+	}
+	renderers[name] = factory
+}
+
+// Formats returns the names of the registered output formats in sorted order.
+func Formats() []string {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// newRenderer creates the Renderer for the configured format.
+func newRenderer(cfg Config) (Renderer, error) {
+	renderersMu.RLock()
+	factory, ok := renderers[cfg.Format]
+	renderersMu.RUnlock()
+
+	if !ok {
+		return nil, &InputError{Err: fmt.Errorf("unsupported output format: %s", cfg.Format)}
+	}
+
+	renderer, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s renderer: %w", cfg.Format, err)
+	}
+	return renderer, nil
+}
+
+// streamRenderer is a Renderer that can also write alerts one at a time as
+// they are fetched, and add them to an existing report.
+type streamRenderer interface {
+	Renderer
+	openStream(filePath string, appendToFile bool) (reportWriter, error)
+}
@@ -0,0 +1,825 @@
+// Package report generates CodeQL alert reports. It reads the alerts to report
+// from input CSV files, or lists every alert of one or more repositories,
+// fetches their details from the GitHub API and writes them in one of several
+// output formats. The gh-generate-codeql-report command is a thin wrapper
+// around Generate.
+package report
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+)
+
+// Config configures a report run. Token or Client.App, and either InputFiles
+// or one of Repository, Organization and ReposFile must be set; the other
+// fields are optional.
+type Config struct {
+	// Token is the GitHub access token used for API requests. It is not used
+	// when Client.App is set.
+	Token string
+	// Client configures the connection to the GitHub API.
+	Client codeql.Options
+	// MaxRetries and RetryDelay configure how transient API errors are
+	// retried, MaxTotalRetries caps the retries across the whole run, and
+	// RequestTimeout limits how long a single request may take. See
+	// codeql.Client for details.
+	MaxRetries      int
+	MaxTotalRetries int
+	// MaxAPICalls caps the number of API requests of the run, counting
+	// retries. Once it is reached, no more records are processed and the
+	// alerts fetched so far are written. Zero means no limit.
+	MaxAPICalls    int
+	RetryDelay     time.Duration
+	RequestTimeout time.Duration
+	// MinWorkers and MaxWorkers bound the number of concurrent API requests.
+	// When zero, one request runs at a time.
+	MinWorkers int
+	MaxWorkers int
+	// WaitForRateLimit makes the run wait for the rate limit to reset before
+	// it starts if it is already nearly used up. Otherwise this is a warning.
+	WaitForRateLimit bool
+
+	// InputFiles are the CSV files listing the alerts to report.
+	InputFiles []string
+	// RepoColumn and AlertColumn name the input columns holding the
+	// owner/name repository and the alert number. When empty, "Repository"
+	// and "Alert Number" are used. If URLColumn is set, alerts are read from
+	// the alert URLs in that column instead.
+	RepoColumn  string
+	AlertColumn string
+	URLColumn   string
+	// URLList reads the input files as lists of alert URLs, one per line,
+	// instead of CSV. Blank lines are ignored.
+	URLList bool
+	// CSV configures how the input files, CSV reports and CSV error output are
+	// read and written. Its Append field is ignored; see Append.
+	CSV csvpkg.Options
+	// Limit is the number of input records processed. Zero means all of them.
+	Limit int
+	// FailOnEmpty makes an input without data rows an error. Otherwise it is
+	// only logged as a warning and an empty report is written.
+	FailOnEmpty bool
+	// Dedupe fetches each alert only once, even if it is listed more than once.
+	Dedupe bool
+	// GroupByRepo processes the input records one repository at a time, with
+	// the repositories sorted by name, and logs a header for each. The report
+	// is written in the same order.
+	GroupByRepo bool
+	// Checkpoint is a file recording the alerts written so far, so that an
+	// interrupted run can be resumed. A resumed run appends to its report.
+	Checkpoint string
+
+	// Repository, Organization and ReposFile report every alert of an
+	// owner/name repository, of an organization, or of each repository listed
+	// in a file, instead of the alerts in InputFiles. Listed alerts have the
+	// given State ("open" when empty) and, if Rules is set, one of those rule
+	// IDs. At most MaxPerRepo are reported per repository unless it is zero.
+	Repository   string
+	Organization string
+	ReposFile    string
+	State        string
+	Rules        []string
+	MaxPerRepo   int
+	// Ref is the git reference alerts are reported on, e.g. refs/heads/main.
+	// When empty, alerts are reported on the default branch.
+	Ref string
+
+	// OutputFile is the path the report is written to, or "-" for standard
+	// output. With SplitBy, it is the directory the report files are written to.
+	OutputFile string
+	// SplitBy splits the report into several files: SplitByRepo writes the
+	// alerts of each repository to a file named by SplitFileName. It cannot be
+	// used with Append or Checkpoint. When empty, a single report is written.
+	SplitBy string
+	// Format is the output format, one of Formats(). When empty, FormatCSV is used.
+	Format string
+	// Append adds alerts to the end of an existing CSV or JSON Lines report
+	// instead of overwriting it.
+	Append bool
+	// Columns are the Alert fields or column headers of a CSV report, in
+	// order. When empty, every column is written.
+	Columns []string
+	// HeaderNames renames the headers of CSV report columns, keyed by Alert
+	// field or default header. The values written in each column are not
+	// changed.
+	HeaderNames map[string]string
+	// NoHeader leaves out the header row of a CSV report.
+	NoHeader bool
+	// Title, if set, starts Markdown, HTML and JSON reports with their Metadata: the
+	// title, when the report was generated, Version, Command and the number of
+	// alerts. A JSON report is then an object with "metadata" and "alerts"
+	// keys instead of an array. Other formats ignore it.
+	Title   string
+	Version string
+	Command string
+	// WithSource, WithSnippet and WithHash fill in the input file, the code
+	// snippet and a stable hash of each alert.
+	WithSource  bool
+	WithSnippet bool
+	WithHash    bool
+	// WithRuleFields reports the rule's own severity and help text, which
+	// are left out otherwise to keep reports small.
+	WithRuleFields bool
+	// WithInstanceCount reports the number of git references each alert was
+	// found on, which takes another request per alert.
+	WithInstanceCount bool
+	// AllInstances reports an alert once for each git reference it was found
+	// on instead of only its most recent instance.
+	AllInstances bool
+	// Instance selects the instance whose location is reported for each
+	// alert, one of Instances. It is not used with Ref or AllInstances. When
+	// empty, InstanceMostRecent is used.
+	Instance string
+	// SortKeys sorts the report by these keys, from SortKeyNames. When empty,
+	// alerts are written in the order they are fetched.
+	SortKeys []string
+	// SeverityMap renames severity levels in the report.
+	SeverityMap map[string]string
+	// ErrorOutput is the CSV or JSON file listing the records that could not
+	// be processed. When empty, no such file is written.
+	ErrorOutput string
+	// RollupFile is the CSV or JSON file to write the number of alerts and
+	// repositories for each rule to. When resuming from a checkpoint, only the
+	// alerts fetched by this run are counted. When empty, no rollup is written.
+	RollupFile string
+
+	// MinSeverity, Tool, PathFilters and Since exclude alerts below a
+	// severity, reported by other tools, at other paths, or created before a
+	// time. IncludeUnranked keeps alerts without a security severity when
+	// MinSeverity is set.
+	MinSeverity     string
+	IncludeUnranked bool
+	Tool            string
+	PathFilters     []string
+	Since           time.Time
+
+	// Logger receives the log of the run. When nil, nothing is logged.
+	Logger *slog.Logger
+	// Verbose logs every excluded alert and writes a message to Messages
+	// for each repository listed and record processed.
+	Verbose bool
+	// Messages receives messages meant for the user running the report.
+	// When nil, they are discarded.
+	Messages io.Writer
+	// Progress, when not nil, is called before each input record is
+	// processed with the number of records done and the total.
+	Progress func(done, total int)
+}
+
+// withDefaults returns a copy of the config with defaults for the fields that
+// are not set.
+func (c Config) withDefaults() Config {
+	if c.RepoColumn == "" {
+		c.RepoColumn = "Repository"
+	}
+	if c.AlertColumn == "" {
+		c.AlertColumn = "Alert Number"
+	}
+	if c.URLList {
+		c.URLColumn = URLListColumn
+	}
+	if c.State == "" {
+		c.State = "open"
+	}
+	if c.Format == "" {
+		c.Format = FormatCSV
+	}
+	if c.Instance == "" {
+		c.Instance = InstanceMostRecent
+	}
+	c.MinWorkers = max(c.MinWorkers, 1)
+	c.MaxWorkers = max(c.MaxWorkers, c.MinWorkers)
+	if c.Logger == nil {
+		c.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if c.Messages == nil {
+		c.Messages = io.Discard
+	}
+	return c
+}
+
+// listMode reports whether alerts are listed from GitHub instead of read from
+// the input files.
+func (c Config) listMode() bool {
+	return c.Repository != "" || c.Organization != "" || c.ReposFile != ""
+}
+
+// Result is the outcome of a report run.
+type Result struct {
+	// Total is the number of alerts to report: the valid input records, or
+	// the alerts listed.
+	Total int
+	// Written is the number of alerts written to the report.
+	Written int
+	// Failed is the number of records and alerts that could not be processed,
+	// and Failures describes each of them.
+	Failed   int
+	Failures []Failure
+	// Skipped is the number of malformed input rows skipped because of
+	// csv.Options.SkipBadRows.
+	Skipped int
+	// Filtered is the number of alerts excluded by a filter, of which
+	// BeforeSince were created before Config.Since.
+	Filtered    int
+	BeforeSince int
+	// Severities counts the written alerts by severity, before they are
+	// renamed by Config.SeverityMap. Alerts without one are counted as "none".
+	Severities map[string]int
+	// ScanningDisabled counts the alerts that could not be fetched by
+	// repository, for repositories without code scanning. Repositories of
+	// Config.ReposFile that could not be listed are counted with no alerts.
+	ScanningDisabled map[string]int
+	// Alerts are the alerts written to the report, in the order they were
+	// fetched and before their severities are renamed.
+	Alerts []codeql.Alert
+	// FetchTimes is the time spent fetching alerts from each owner/repo
+	// repository, for alerts read from the input.
+	FetchTimes map[string]FetchTime
+}
+
+// FetchTime is the time spent fetching the alerts of one repository.
+type FetchTime struct {
+	Repository string
+	// Fetches is the number of alerts fetched, including failed fetches.
+	Fetches int
+	Total   time.Duration
+	Slowest time.Duration
+}
+
+// SlowestRepositories returns the n repositories that took the longest to
+// fetch in total, slowest first.
+func (r *Result) SlowestRepositories(n int) []FetchTime {
+	times := slices.Collect(maps.Values(r.FetchTimes))
+	slices.SortFunc(times, func(a, b FetchTime) int {
+		if a.Total != b.Total {
+			return cmp.Compare(b.Total, a.Total)
+		}
+		return strings.Compare(a.Repository, b.Repository)
+	})
+	return times[:min(n, len(times))]
+}
+
+// addFetchTime records how long fetching the alert referenced by ref took.
+func (r *Result) addFetchTime(ref AlertRef, duration time.Duration) {
+	repo := ref.Owner + "/" + ref.Repo
+	t := r.FetchTimes[repo]
+	t.Repository = repo
+	t.Fetches++
+	t.Total += duration
+	t.Slowest = max(t.Slowest, duration)
+	r.FetchTimes[repo] = t
+}
+
+// newResult creates an empty Result.
+func newResult() *Result {
+	return &Result{
+		Severities:       make(map[string]int),
+		ScanningDisabled: make(map[string]int),
+		FetchTimes:       make(map[string]FetchTime),
+	}
+}
+
+// addAlert records an alert that was written to the report.
+func (r *Result) addAlert(alert codeql.Alert) {
+	severity := alert.Severity
+	if severity == "" {
+		severity = "none"
+	}
+	r.Severities[severity]++
+	r.Written++
+	r.Alerts = append(r.Alerts, alert)
+}
+
+// CountAtLeast returns how many written alerts are at or above the given severity.
+func (r *Result) CountAtLeast(level string) int {
+	threshold := codeql.SeverityRank(level)
+	count := 0
+	for severity, n := range r.Severities {
+		if codeql.SeverityRank(severity) >= threshold {
+			count += n
+		}
+	}
+	return count
+}
+
+// addFiltered records the alerts excluded by a filter.
+func (r *Result) addFiltered(counts filterCounts) {
+	r.Filtered += counts.excluded
+	r.BeforeSince += counts.beforeSince
+}
+
+// addFailure records an input record or alert that could not be processed.
+func (r *Result) addFailure(failure Failure) {
+	r.Failures = append(r.Failures, failure)
+	r.Failed++
+}
+
+// addScanningDisabled records an alert that could not be fetched because code
+// scanning is not enabled for its repository.
+func (r *Result) addScanningDisabled(ref AlertRef, err error) {
+	r.addFailure(newFailedRef(ref, err))
+	r.ScanningDisabled[ref.Owner+"/"+ref.Repo]++
+}
+
+// addScanningDisabledRepo records a repository of Config.ReposFile that could
+// not be listed because code scanning is not enabled for it, with no alerts.
+func (r *Result) addScanningDisabledRepo(fullName string, failure Failure) {
+	r.addFailure(failure)
+	if _, ok := r.ScanningDisabled[fullName]; !ok {
+		r.ScanningDisabled[fullName] = 0
+	}
+}
+
+// InputError is returned when a run fails because of invalid configuration or
+// input, such as an unreadable input file or a token that cannot read alerts.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// NewClient creates the API client configured by cfg.
+func NewClient(cfg Config) (*codeql.Client, error) {
+	cfg = cfg.withDefaults()
+
+	client, err := codeql.NewClient(cfg.Token, cfg.Logger, cfg.Client)
+	if err != nil {
+		return nil, err
+	}
+	client.MaxRetries = cfg.MaxRetries
+	client.MaxTotalRetries = cfg.MaxTotalRetries
+	client.MaxAPICalls = cfg.MaxAPICalls
+	client.RequestTimeout = cfg.RequestTimeout
+	client.RetryDelay = cfg.RetryDelay
+	return client, nil
+}
+
+// CheckToken runs the token scope preflight check. A token that is rejected or
+// lacks the required scope is reported as an InputError.
+func CheckToken(ctx context.Context, client *codeql.Client) error {
+	err := client.CheckTokenScopes(ctx)
+	if errors.Is(err, codeql.ErrMissingScope) || errors.Is(err, codeql.ErrUnauthorized) {
+		return &InputError{Err: err}
+	}
+	return err
+}
+
+// generator holds the state of a single report run.
+type generator struct {
+	cfg     Config
+	logger  *slog.Logger
+	client  *codeql.Client
+	columns []column
+	result  *Result
+}
+
+// checkRateLimit logs the rate limit reported by the token check, so a run
+// that is about to use it up is noticed before it starts rather than when it
+// stalls. A nearly used up rate limit is a warning, or with
+// Config.WaitForRateLimit is waited out.
+func (g *generator) checkRateLimit(ctx context.Context) error {
+	rate := g.client.RateStatus()
+	if rate == nil || rate.Limit == 0 {
+		g.logger.Info("Rate limit not reported, skipping the rate limit check")
+		return nil
+	}
+	g.logger.Info("GitHub rate limit", codeql.RateAttrs(*rate)...)
+	if rate.Remaining >= codeql.LowRateLimit {
+		return nil
+	}
+
+	wait := time.Until(rate.Reset.Time)
+	if !g.cfg.WaitForRateLimit || wait <= 0 {
+		g.logger.Warn("GitHub rate limit is nearly used up", codeql.RateAttrs(*rate)...)
+		fmt.Fprintf(g.cfg.Messages, "GitHub rate limit is nearly used up: %d requests remaining until %v\n", rate.Remaining, rate.Reset.Time)
+		return nil
+	}
+
+	g.logger.Info("GitHub rate limit is nearly used up, waiting until it resets", append(codeql.RateAttrs(*rate), slog.Duration("wait", wait.Round(time.Second)))...)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Generate collects the alerts configured by cfg and writes the report. Alerts
+// collected before an error are still written to the report. The result is nil
+// if the run failed before any alert was processed.
+func Generate(ctx context.Context, cfg Config) (result *Result, err error) {
+	g := &generator{cfg: cfg.withDefaults()}
+	g.logger = g.cfg.Logger
+
+	if err := ValidateSortKeys(g.cfg.SortKeys); err != nil {
+		return nil, &InputError{Err: err}
+	}
+	if g.cfg.SplitBy != "" {
+		if g.cfg.SplitBy != SplitByRepo {
+			return nil, &InputError{Err: fmt.Errorf("invalid split %q: must be %s", g.cfg.SplitBy, SplitByRepo)}
+		}
+		if g.cfg.Append || g.cfg.Checkpoint != "" || g.cfg.OutputFile == StdoutPath {
+			return nil, &InputError{Err: fmt.Errorf("a report split by %s cannot be appended to, checkpointed or written to standard output", g.cfg.SplitBy)}
+		}
+	}
+	if !slices.Contains(Instances, g.cfg.Instance) {
+		return nil, &InputError{Err: fmt.Errorf("invalid instance %q: must be one of %s", g.cfg.Instance, strings.Join(Instances, ", "))}
+	}
+	if g.columns, err = resolveColumns(&g.cfg); err != nil {
+		return nil, &InputError{Err: err}
+	}
+
+	if g.client, err = NewClient(g.cfg); err != nil {
+		return nil, &InputError{Err: err}
+	}
+
+	// Fail fast if the token cannot read alerts
+	if err := CheckToken(ctx, g.client); err != nil {
+		return nil, err
+	}
+	if err := g.checkRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	// Read the input before creating the output file so bad input leaves no report behind
+	var refs []AlertRef
+	var skipped int
+	var invalid []Failure
+	if !g.cfg.listMode() {
+		var records []Record
+		records, skipped, err = ReadRecords(g.cfg)
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+
+		refs, invalid = ParseRecords(g.cfg, records)
+		if g.cfg.Dedupe {
+			refs = DedupeRefs(refs, g.logger)
+		}
+		if g.cfg.GroupByRepo {
+			refs = GroupRefsByRepo(refs)
+		}
+	}
+
+	// Skip alerts written by a previous run and append to its report
+	var cp *checkpoint
+	var resume bool
+	if g.cfg.Checkpoint != "" {
+		cp, err = openCheckpoint(g.cfg.Checkpoint)
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+		defer cp.Close()
+
+		resume = cp.len() > 0
+		refs = g.skipCheckpointed(refs, cp)
+	}
+
+	// Alerts are written as soon as they are fetched
+	writer, err := g.newReportWriter(resume || g.cfg.Append)
+	if err != nil {
+		return nil, err
+	}
+	// Severities are renamed last so sorting and the result see the levels
+	if len(g.cfg.SeverityMap) > 0 {
+		writer = &severityMappingWriter{writer: writer, mappings: g.cfg.SeverityMap}
+	}
+	if len(g.cfg.SortKeys) > 0 {
+		writer = &sortingReportWriter{writer: writer, keys: g.cfg.SortKeys}
+	}
+
+	g.result = newResult()
+	g.result.Skipped = skipped
+	for _, failure := range invalid {
+		g.result.addFailure(failure)
+	}
+
+	// Always close the writer, so the alerts collected so far are kept even if processing fails
+	defer func() {
+		if closeErr := writer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if g.cfg.RollupFile != "" {
+			if writeErr := g.writeRollup(g.cfg.RollupFile, g.result.Alerts); writeErr != nil && err == nil {
+				err = writeErr
+			}
+		}
+		if g.cfg.ErrorOutput != "" {
+			if writeErr := g.writeFailures(g.cfg.ErrorOutput, g.result.Failures); writeErr != nil && err == nil {
+				err = writeErr
+			}
+		}
+	}()
+
+	if g.cfg.listMode() {
+		return g.result, g.writeListedAlerts(ctx, writer)
+	}
+	return g.result, g.writeInputAlerts(ctx, writer, refs, cp)
+}
+
+// writeListedAlerts writes every alert for the configured repository, the
+// organization, or each repository listed in the repositories file.
+func (g *generator) writeListedAlerts(ctx context.Context, writer reportWriter) error {
+	cfg := g.cfg
+	opts := codeql.ListOptions{State: cfg.State, Ref: cfg.Ref, ToolName: cfg.Tool, RuleIDs: cfg.Rules, Limit: cfg.MaxPerRepo}
+
+	if cfg.Organization != "" {
+		g.verbosef("Listing %s alerts for %s\n", cfg.State, cfg.Organization)
+		alerts, err := g.client.ListAlertsForOrg(ctx, cfg.Organization, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list alerts for %s: %w", cfg.Organization, err)
+		}
+		return g.writeAlertList(ctx, writer, alerts)
+	}
+
+	if cfg.ReposFile == "" {
+		g.verbosef("Listing %s alerts for %s\n", cfg.State, cfg.Repository)
+		owner, repo, err := SplitRepository(cfg.Repository)
+		if err != nil {
+			return &InputError{Err: err}
+		}
+		alerts, err := g.client.ListAlerts(ctx, owner, repo, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list alerts for %s: %w", cfg.Repository, err)
+		}
+		return g.writeAlertList(ctx, writer, alerts)
+	}
+
+	repos, err := ReadReposFile(cfg.ReposFile, g.logger)
+	if err != nil {
+		return &InputError{Err: err}
+	}
+
+	// A repository that cannot be listed is recorded as a failure and skipped
+	for _, fullName := range repos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		g.verbosef("Listing %s alerts for %s\n", cfg.State, fullName)
+
+		owner, repo, err := SplitRepository(fullName)
+		if err == nil {
+			var alerts []codeql.Alert
+			if alerts, err = g.client.ListAlerts(ctx, owner, repo, opts); err == nil {
+				if err := g.writeAlertList(ctx, writer, alerts); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		failure := Failure{Repository: fullName, Source: cfg.ReposFile, Error: err.Error()}
+		if errors.Is(err, codeql.ErrScanningDisabled) {
+			g.logger.Warn("code scanning is not enabled, skipping the repository", slog.String("repo", fullName))
+			g.result.addScanningDisabledRepo(fullName, failure)
+			continue
+		}
+
+		// No more requests can be made, so stop with the alerts written so far
+		if errors.Is(err, codeql.ErrAPICallBudget) {
+			return fmt.Errorf("failed to list alerts for %s: %w", fullName, err)
+		}
+
+		g.logger.Error("Failed to list alerts", slog.String("repo", fullName), slog.Any("error", err))
+		g.result.addFailure(failure)
+
+		// Every remaining request would be rejected with the same token
+		if errors.Is(err, codeql.ErrUnauthorized) {
+			return fmt.Errorf("failed to list alerts for %s: %w", fullName, err)
+		}
+	}
+	return nil
+}
+
+// writeAlertList writes listed alerts, with the configured instances.
+func (g *generator) writeAlertList(ctx context.Context, writer reportWriter, alerts []codeql.Alert) error {
+	g.result.Total += len(alerts)
+	for _, alert := range alerts {
+		rows, err := g.selectInstances(ctx, alert)
+		if errors.Is(err, codeql.ErrAPICallBudget) {
+			return err
+		}
+		if err != nil {
+			g.logger.Error("Failed to get alert instances", append(codeql.AlertAttrs(alert.Owner, alert.Repo, int64(alert.ID)), slog.Any("error", err))...)
+			g.result.addFailure(newFailedRef(AlertRef{Owner: alert.Owner, Repo: alert.Repo, Number: int64(alert.ID)}, err))
+			continue
+		}
+		rows, filtered := g.filterAlerts(rows)
+		g.result.addFiltered(filtered)
+		g.addSnippets(ctx, rows)
+		g.addInstanceCounts(ctx, rows)
+
+		for _, row := range rows {
+			if err := g.emitAlert(writer, row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeInputAlerts fetches and writes each alert referenced by the input records,
+// recording each one in the checkpoint, if any, once it has been processed.
+func (g *generator) writeInputAlerts(ctx context.Context, writer reportWriter, refs []AlertRef, cp *checkpoint) error {
+	g.result.Total = len(refs)
+
+	// Stop the workers when returning early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limiter := codeql.NewLimiter(g.cfg.MinWorkers, g.cfg.MaxWorkers, g.logger)
+	g.client.Limiter = limiter
+	results := g.fetchAlerts(ctx, limiter, refs)
+
+	// Alerts are fetched concurrently but written in input order, so hold
+	// results that arrive early until the ones before them are written
+	pending := make(map[int]fetchResult)
+	start := time.Now()
+	for i := 0; i < len(refs); {
+		// Stop once the run has timed out rather than failing every remaining
+		// record, but keep the alerts that were already fetched
+		if err := ctx.Err(); err != nil {
+			written, writeErr := g.writeFetched(writer, refs, pending, results, cp)
+			if writeErr != nil {
+				return stoppedAfter(i+written, len(refs), writeErr)
+			}
+			if written > 0 {
+				g.logger.Info("Processed records fetched before the run stopped", slog.Int("records", written))
+			}
+			return fmt.Errorf("stopped after %d of %d records: %w", i+written, len(refs), err)
+		}
+
+		result, ok := pending[i]
+		if !ok {
+			select {
+			case next, ok := <-results:
+				if ok {
+					pending[next.index] = next
+				}
+			case <-ctx.Done():
+			}
+			continue
+		}
+		delete(pending, i)
+
+		if i > 0 && i%rateProjectionInterval == 0 {
+			g.logRateProjection(i, len(refs)-i, time.Since(start))
+		}
+
+		if g.cfg.Progress != nil {
+			g.cfg.Progress(i, len(refs))
+		}
+		i++
+
+		if err := g.writeResult(writer, refs[i-1], result, cp); err != nil {
+			return stoppedAfter(i, len(refs), err)
+		}
+	}
+
+	g.logger.Info("Processed alerts", slog.Int("written", g.result.Written), slog.Int("total", len(refs)))
+	if g.result.Failed > 0 {
+		g.logger.Error("Failed to process alerts", slog.Int("failed", g.result.Failed))
+	}
+
+	return nil
+}
+
+// writeResult writes the alerts fetched for ref, or records why they could
+// not be fetched. It returns an error if no further records should be
+// processed.
+func (g *generator) writeResult(writer reportWriter, ref AlertRef, result fetchResult, cp *checkpoint) error {
+	g.result.addFetchTime(ref, result.duration)
+
+	if errors.Is(result.err, codeql.ErrScanningDisabled) {
+		g.logger.Warn("code scanning is not enabled, skipping the alert", codeql.AlertAttrs(ref.Owner, ref.Repo, ref.Number)...)
+		g.result.addScanningDisabled(ref, result.err)
+		return nil
+	}
+
+	// No more requests can be made, so stop with the alerts written so far
+	if errors.Is(result.err, codeql.ErrAPICallBudget) {
+		return result.err
+	}
+
+	if result.err != nil {
+		g.logger.Error("Failed to get alert", append(codeql.AlertAttrs(ref.Owner, ref.Repo, ref.Number), slog.Any("error", result.err))...)
+		g.result.addFailure(newFailedRef(ref, result.err))
+
+		// Every remaining request would be rejected with the same token
+		if errors.Is(result.err, codeql.ErrUnauthorized) {
+			return result.err
+		}
+		return nil
+	}
+
+	g.result.addFiltered(result.filtered)
+	for _, alert := range result.alerts {
+		if err := g.emitAlert(writer, alert); err != nil {
+			return err
+		}
+	}
+
+	if cp != nil {
+		if err := cp.add(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFetched writes the results fetched before the run was cut short, once
+// the workers have stopped. Writing the report needs no API calls, so it is
+// done even though ctx is done. Results are written in input order, skipping
+// the records whose fetch was cut short. It returns the number of records
+// processed.
+func (g *generator) writeFetched(writer reportWriter, refs []AlertRef, pending map[int]fetchResult, results <-chan fetchResult, cp *checkpoint) (int, error) {
+	for result := range results {
+		pending[result.index] = result
+	}
+
+	written := 0
+	for _, index := range slices.Sorted(maps.Keys(pending)) {
+		result := pending[index]
+		if errors.Is(result.err, context.Canceled) || errors.Is(result.err, context.DeadlineExceeded) {
+			continue
+		}
+		if err := g.writeResult(writer, refs[index], result, cp); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// stoppedAfter explains why the records after the first done were not
+// processed. Errors that end a run early, such as a rejected token, are
+// reported with the number of records processed.
+func stoppedAfter(done, total int, err error) error {
+	if errors.Is(err, codeql.ErrAPICallBudget) || errors.Is(err, codeql.ErrUnauthorized) {
+		return fmt.Errorf("stopped after %d of %d records: %w", done, total, err)
+	}
+	return err
+}
+
+// verbosef writes a message to cfg.Messages in verbose runs.
+func (g *generator) verbosef(format string, args ...any) {
+	if g.cfg.Verbose {
+		fmt.Fprintf(g.cfg.Messages, format, args...)
+	}
+}
+
+// rateProjectionInterval is how often, in records, the rate limit projection is logged.
+const rateProjectionInterval = 100
+
+// logRateProjection logs whether the records left can be fetched within the
+// remaining rate limit and, if not, roughly when the limit will be hit.
+func (g *generator) logRateProjection(done, left int, elapsed time.Duration) {
+	rate := g.client.RateStatus()
+	if rate == nil || done == 0 {
+		return
+	}
+
+	if left <= rate.Remaining {
+		g.logger.Info("Rate limit is enough for the records left", append(codeql.RateAttrs(*rate), slog.Int("records_left", left))...)
+		return
+	}
+
+	// Estimate when the remaining budget runs out at the current pace
+	perRecord := elapsed / time.Duration(done)
+	exhaustedAt := time.Now().Add(perRecord * time.Duration(rate.Remaining))
+	if exhaustedAt.After(rate.Reset.Time) {
+		g.logger.Info("Rate limit resets before it is expected to run out", append(codeql.RateAttrs(*rate), slog.Int("records_left", left))...)
+		return
+	}
+
+	g.logger.Info("Rate limit is expected to run out before the records left are fetched",
+		append(codeql.RateAttrs(*rate), slog.Int("records_left", left), slog.Time("exhausted_at", exhaustedAt.Round(time.Second)))...)
+}
+
+// FormatTime formats t as RFC 3339, or returns an empty string for the zero time.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
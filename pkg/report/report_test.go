@@ -0,0 +1,227 @@
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// fakeGitHub is a GitHub API server that reports a rate limit and serves an
+// alert with a single instance for every alert number requested, counting the
+// alert and instance requests.
+type fakeGitHub struct {
+	*httptest.Server
+	alertRequests    atomic.Int64
+	instanceRequests atomic.Int64
+	// beforeAlert, if not nil, is called before each alert is served with the
+	// number of alert requests so far, including this one.
+	beforeAlert func(r *http.Request, requests int64)
+}
+
+// newFakeGitHub starts a fakeGitHub that is stopped when the test ends.
+func newFakeGitHub(t *testing.T, beforeAlert func(r *http.Request, requests int64)) *fakeGitHub {
+	t.Helper()
+	f := &fakeGitHub{beforeAlert: beforeAlert}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// serveHTTP serves the rate limit, alert, alert instance and alert list
+// endpoints. Alerts of repositories named "disabled" cannot be listed because
+// code scanning is not enabled for them; other repositories have none.
+func (f *fakeGitHub) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/rate_limit"):
+		fmt.Fprint(w, `{"resources":{"core":{"limit":5000,"remaining":5000,"reset":1893456000}}}`)
+	case strings.HasSuffix(r.URL.Path, "/instances"):
+		f.instanceRequests.Add(1)
+		fmt.Fprint(w, `[{"ref":"refs/heads/main","state":"open","location":{"path":"main.go","start_line":1}}]`)
+	case strings.HasSuffix(r.URL.Path, "/disabled/code-scanning/alerts"):
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"Code scanning is not enabled for this repository"}`)
+	case strings.HasSuffix(r.URL.Path, "/code-scanning/alerts"):
+		fmt.Fprint(w, `[]`)
+	case strings.Contains(r.URL.Path, "/code-scanning/alerts/"):
+		requests := f.alertRequests.Add(1)
+		if f.beforeAlert != nil {
+			f.beforeAlert(r, requests)
+		}
+		number := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		fmt.Fprintf(w, `{"number":%s,"state":"open","rule":{"id":"go/sql-injection","security_severity_level":"high"},`+
+			`"tool":{"name":"CodeQL"},"most_recent_instance":{"location":{"path":"main.go","start_line":1}}}`, number)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// testConfig returns a Config reading alert numbers of org/repo from input,
+// fetching them from server and writing a CSV report to output.
+func testConfig(server *fakeGitHub, input, output string) Config {
+	return Config{
+		Token:      "test-token",
+		Client:     codeql.Options{BaseURL: server.URL + "/"},
+		InputFiles: []string{input},
+		OutputFile: output,
+	}
+}
+
+// writeInput writes an input CSV listing the given alert numbers of org/repo
+// and returns its path.
+func writeInput(t *testing.T, numbers ...string) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("Repository,Alert Number\n")
+	for _, number := range numbers {
+		fmt.Fprintf(&b, "org/repo,%s\n", number)
+	}
+	path := filepath.Join(t.TempDir(), "input.csv")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	return path
+}
+
+// readRows reads a CSV report and returns its rows, without the header row.
+func readRows(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open report: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	if len(records) == 0 {
+		t.Fatalf("report %s has no header row", path)
+	}
+	return records[1:]
+}
+
+func TestGenerateCreatesOutputDirectory(t *testing.T) {
+	server := newFakeGitHub(t, nil)
+	output := filepath.Join(t.TempDir(), "a", "b", "c", "report.csv")
+
+	result, err := Generate(context.Background(), testConfig(server, writeInput(t, "1", "2"), output))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Written != 2 {
+		t.Errorf("Written = %d, want 2", result.Written)
+	}
+	if rows := readRows(t, output); len(rows) != 2 {
+		t.Errorf("report has %d rows, want 2", len(rows))
+	}
+}
+
+func TestGenerateKeepsAlertsFetchedBeforeCancel(t *testing.T) {
+	const fetched = 3
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel the run on the first fetch after the first few, and hold that
+	// fetch until it is abandoned so it never completes
+	server := newFakeGitHub(t, func(r *http.Request, requests int64) {
+		if requests > fetched {
+			cancel()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	})
+	output := filepath.Join(t.TempDir(), "report.csv")
+
+	cfg := testConfig(server, writeInput(t, "1", "2", "3", "4", "5", "6"), output)
+	cfg.Columns = []string{"ID"}
+	result, err := Generate(ctx, cfg)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Generate() error = %v, want %v", err, context.Canceled)
+	}
+	if result.Written != fetched {
+		t.Errorf("Written = %d, want %d", result.Written, fetched)
+	}
+
+	rows := readRows(t, output)
+	if len(rows) != fetched {
+		t.Fatalf("report has %d rows, want %d", len(rows), fetched)
+	}
+	for i, row := range rows {
+		if want := strconv.Itoa(i + 1); row[0] != want {
+			t.Errorf("row %d is alert #%s, want alert #%s", i, row[0], want)
+		}
+	}
+}
+
+func TestGenerateSkipsRequestsForFilteredAlerts(t *testing.T) {
+	server := newFakeGitHub(t, nil)
+	output := filepath.Join(t.TempDir(), "report.csv")
+
+	cfg := testConfig(server, writeInput(t, "1", "2", "3"), output)
+	cfg.WithInstanceCount = true
+	cfg.Tool = "Semgrep"
+	result, err := Generate(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Filtered != 3 || result.Written != 0 {
+		t.Errorf("Filtered = %d, Written = %d, want 3 and 0", result.Filtered, result.Written)
+	}
+	if requests := server.instanceRequests.Load(); requests != 0 {
+		t.Errorf("made %d instance requests for excluded alerts, want none", requests)
+	}
+
+	// The same alerts are counted once they pass the filter
+	cfg.Tool = "CodeQL"
+	result, err = Generate(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if result.Written != 3 {
+		t.Errorf("Written = %d, want 3", result.Written)
+	}
+	if requests := server.instanceRequests.Load(); requests != 3 {
+		t.Errorf("made %d instance requests, want 3", requests)
+	}
+}
+
+func TestGenerateCountsReposWithoutCodeScanning(t *testing.T) {
+	server := newFakeGitHub(t, nil)
+	dir := t.TempDir()
+	reposFile := filepath.Join(dir, "repos.txt")
+	if err := os.WriteFile(reposFile, []byte("org/repo\norg/disabled\n"), 0644); err != nil {
+		t.Fatalf("failed to write repositories file: %v", err)
+	}
+
+	cfg := testConfig(server, "", filepath.Join(dir, "report.csv"))
+	cfg.InputFiles = nil
+	cfg.ReposFile = reposFile
+	result, err := Generate(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := map[string]int{"org/disabled": 0}
+	if !maps.Equal(result.ScanningDisabled, want) {
+		t.Errorf("ScanningDisabled = %v, want %v", result.ScanningDisabled, want)
+	}
+	if result.Failed != 1 {
+		t.Errorf("Failed = %d, want 1", result.Failed)
+	}
+}
@@ -0,0 +1,122 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+	csvpkg "github.com/lindluni/gh-generate-codeql-report/pkg/csv"
+	jsonpkg "github.com/lindluni/gh-generate-codeql-report/pkg/json"
+)
+
+// RuleRollup summarizes the alerts reported by a single rule.
+type RuleRollup struct {
+	RuleID      string `json:"rule_id"`
+	Description string `json:"description"`
+	// Severity is the highest severity of the rule's alerts.
+	Severity     string `json:"severity"`
+	Alerts       int    `json:"alerts"`
+	Repositories int    `json:"repositories"`
+}
+
+// Rollup groups alerts by rule, counting the alerts and the repositories they
+// are in. Rules are ordered by severity, most severe first, then by the number
+// of alerts and by rule ID.
+func Rollup(alerts []codeql.Alert) []RuleRollup {
+	rollups := make(map[string]*RuleRollup)
+	repos := make(map[string]map[string]bool)
+
+	for _, alert := range alerts {
+		rollup, ok := rollups[alert.RuleID]
+		if !ok {
+			rollup = &RuleRollup{RuleID: alert.RuleID, Description: alert.ShortDesc, Severity: alert.Severity}
+			rollups[alert.RuleID] = rollup
+			repos[alert.RuleID] = make(map[string]bool)
+		}
+
+		rollup.Alerts++
+		if codeql.SeverityRank(alert.Severity) > codeql.SeverityRank(rollup.Severity) {
+			rollup.Severity = alert.Severity
+		}
+
+		repo := strings.ToLower(alert.Owner + "/" + alert.Repo)
+		if !repos[alert.RuleID][repo] {
+			repos[alert.RuleID][repo] = true
+			rollup.Repositories++
+		}
+	}
+
+	result := make([]RuleRollup, 0, len(rollups))
+	for _, rollup := range rollups {
+		result = append(result, *rollup)
+	}
+	slices.SortFunc(result, func(a, b RuleRollup) int {
+		if ra, rb := codeql.SeverityRank(a.Severity), codeql.SeverityRank(b.Severity); ra != rb {
+			return rb - ra
+		}
+		if a.Alerts != b.Alerts {
+			return b.Alerts - a.Alerts
+		}
+		return strings.Compare(a.RuleID, b.RuleID)
+	})
+	return result
+}
+
+// rollupRenderer renders the alerts-by-rule rollup of a report, as JSON or
+// as CSV. Severities are renamed by severityMap once the rules are ordered.
+type rollupRenderer struct {
+	json        bool
+	opts        csvpkg.Options
+	severityMap map[string]string
+}
+
+// Write writes the rollup of alerts to out.
+func (r rollupRenderer) Write(alerts []codeql.Alert, out io.Writer) error {
+	rollups := Rollup(alerts)
+	for i := range rollups {
+		if label, ok := r.severityMap[rollups[i].Severity]; ok {
+			rollups[i].Severity = label
+		}
+	}
+
+	if r.json {
+		return jsonpkg.Encode(out, rollups)
+	}
+
+	headers := []string{"Rule ID", "Description", "Severity", "Alerts", "Repositories"}
+	rows := make([][]string, 0, len(rollups))
+	for _, rollup := range rollups {
+		rows = append(rows, []string{rollup.RuleID, rollup.Description, rollup.Severity,
+			strconv.Itoa(rollup.Alerts), strconv.Itoa(rollup.Repositories)})
+	}
+	return csvpkg.Encode(out, headers, rows, r.opts)
+}
+
+// writeRollup writes the rollup of the alerts written to the report to path,
+// as JSON if it has a .json extension and otherwise as CSV.
+func (g *generator) writeRollup(path string, alerts []codeql.Alert) error {
+	if err := g.createParentDir(path); err != nil {
+		return fmt.Errorf("failed to write rollup: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	renderer := rollupRenderer{
+		json:        strings.EqualFold(filepath.Ext(path), ".json"),
+		opts:        g.cfg.csvOptions(),
+		severityMap: g.cfg.SeverityMap,
+	}
+	if err := renderer.Write(alerts, f); err != nil {
+		return fmt.Errorf("failed to write rollup: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,25 @@
+package report
+
+import (
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// severityMappingWriter renames alert severities using the severity map before
+// passing alerts to the underlying writer.
+type severityMappingWriter struct {
+	writer   reportWriter
+	mappings map[string]string
+}
+
+// WriteAlert writes the alert with its severity renamed, if it is mapped.
+func (w *severityMappingWriter) WriteAlert(alert codeql.Alert) error {
+	if label, ok := w.mappings[alert.Severity]; ok {
+		alert.Severity = label
+	}
+	return w.writer.WriteAlert(alert)
+}
+
+// Close closes the underlying writer.
+func (w *severityMappingWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// alertComparators compares alerts by each sort key.
+var alertComparators = map[string]func(a, b codeql.Alert) int{
+	"org": func(a, b codeql.Alert) int {
+		return strings.Compare(strings.ToLower(a.Owner), strings.ToLower(b.Owner))
+	},
+	"repo": func(a, b codeql.Alert) int {
+		return cmp.Or(
+			strings.Compare(strings.ToLower(a.Owner), strings.ToLower(b.Owner)),
+			strings.Compare(strings.ToLower(a.Repo), strings.ToLower(b.Repo)),
+		)
+	},
+	// Most severe first
+	"severity": func(a, b codeql.Alert) int {
+		return cmp.Compare(codeql.SeverityRank(b.Severity), codeql.SeverityRank(a.Severity))
+	},
+	"id": func(a, b codeql.Alert) int {
+		return cmp.Compare(a.ID, b.ID)
+	},
+}
+
+// SortKeyNames lists the keys a report can be sorted by.
+var SortKeyNames = []string{"org", "repo", "severity", "id"}
+
+// ValidateSortKeys checks that every sort key is one of SortKeyNames.
+func ValidateSortKeys(keys []string) error {
+	for _, key := range keys {
+		if _, ok := alertComparators[key]; !ok {
+			return fmt.Errorf("invalid sort key %q: must be one of %s", key, strings.Join(SortKeyNames, ", "))
+		}
+	}
+	return nil
+}
+
+// sortAlerts sorts alerts by the keys in order. The sort is stable, so alerts
+// that are equal on every key keep their input order.
+func sortAlerts(alerts []codeql.Alert, keys []string) {
+	slices.SortStableFunc(alerts, func(a, b codeql.Alert) int {
+		for _, key := range keys {
+			if c := alertComparators[key](a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// sortingReportWriter collects alerts and writes them to the underlying
+// writer in sorted order when closed.
+type sortingReportWriter struct {
+	writer reportWriter
+	keys   []string
+	alerts []codeql.Alert
+}
+
+// WriteAlert adds the alert to the report.
+func (w *sortingReportWriter) WriteAlert(alert codeql.Alert) error {
+	w.alerts = append(w.alerts, alert)
+	return nil
+}
+
+// Close sorts the collected alerts, writes them and closes the underlying writer.
+func (w *sortingReportWriter) Close() error {
+	sortAlerts(w.alerts, w.keys)
+
+	for _, alert := range w.alerts {
+		if err := w.writer.WriteAlert(alert); err != nil {
+			w.writer.Close()
+			return err
+		}
+	}
+	return w.writer.Close()
+}
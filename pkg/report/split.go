@@ -0,0 +1,134 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// SplitByRepo splits the report into one file per repository, for
+// Config.SplitBy.
+const SplitByRepo = "repo"
+
+// SplitFileName returns the name of the file holding the alerts of the
+// owner/repo repository when the report is split by repository. Names are not
+// unique, as owner and repository names can contain "-", so the writer adds a
+// suffix to the names that are taken.
+func SplitFileName(owner, repo, format string) string {
+	return splitFileName(owner+"-"+repo, format)
+}
+
+// splitFileName returns the name of a split report file for the given base name.
+func splitFileName(base, format string) string {
+	return fmt.Sprintf("report-%s%s", base, FormatExtensions(format)[0])
+}
+
+// splitReportWriter writes the alerts of each repository to a file of its own
+// in a directory. Alerts are collected until the writer is closed, so only one
+// file is open at a time however many repositories there are.
+type splitReportWriter struct {
+	dir      string
+	format   string
+	renderer Renderer
+	logger   *slog.Logger
+	// repos maps lowercase owner/repo names to the alerts of the repository.
+	repos map[string][]codeql.Alert
+}
+
+// newSplitReportWriter creates the output directory and returns a
+// splitReportWriter writing to it.
+func newSplitReportWriter(dir, format string, renderer Renderer, logger *slog.Logger) (*splitReportWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+	return &splitReportWriter{
+		dir:      dir,
+		format:   format,
+		renderer: renderer,
+		logger:   logger,
+		repos:    make(map[string][]codeql.Alert),
+	}, nil
+}
+
+// WriteAlert adds the alert to the report of its repository. Repositories are
+// compared case-insensitively, as on GitHub.
+func (w *splitReportWriter) WriteAlert(alert codeql.Alert) error {
+	key := strings.ToLower(alert.Owner + "/" + alert.Repo)
+	w.repos[key] = append(w.repos[key], alert)
+	return nil
+}
+
+// Close renders the report of each repository to its file. A file that cannot
+// be written does not stop the others from being written.
+func (w *splitReportWriter) Close() error {
+	keys := slices.Sorted(maps.Keys(w.repos))
+	names := w.fileNames(keys)
+
+	var errs []error
+	for _, key := range keys {
+		path := filepath.Join(w.dir, names[key])
+		if err := w.writeFile(path, w.repos[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fileNames returns the file name of each repository, by key. A repository
+// whose SplitFileName is the same as another's, such as a-b/c and a/b-c, gets
+// a numbered suffix so neither file overwrites the other. Repositories keep
+// the name from SplitFileName where they can, so the names of other
+// repositories do not change when a colliding one is added.
+func (w *splitReportWriter) fileNames(keys []string) map[string]string {
+	names := make(map[string]string, len(keys))
+	taken := make(map[string]bool, len(keys))
+	var colliding []string
+	for _, key := range keys {
+		alert := w.repos[key][0]
+		name := SplitFileName(alert.Owner, alert.Repo, w.format)
+		// File names are compared case-insensitively, as on macOS and Windows
+		if taken[strings.ToLower(name)] {
+			colliding = append(colliding, key)
+			continue
+		}
+		names[key] = name
+		taken[strings.ToLower(name)] = true
+	}
+
+	for _, key := range colliding {
+		alert := w.repos[key][0]
+		var name string
+		for n := 2; ; n++ {
+			name = splitFileName(fmt.Sprintf("%s-%s-%d", alert.Owner, alert.Repo, n), w.format)
+			if !taken[strings.ToLower(name)] {
+				break
+			}
+		}
+		names[key] = name
+		taken[strings.ToLower(name)] = true
+		w.logger.Warn("Report file name is taken by another repository, adding a suffix",
+			slog.String("repo", alert.Owner+"/"+alert.Repo), slog.String("file", name))
+	}
+	return names
+}
+
+// writeFile renders alerts to the file at path.
+func (w *splitReportWriter) writeFile(path string, alerts []codeql.Alert) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := w.renderer.Write(alerts, f); err != nil {
+		return fmt.Errorf("failed to write output %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,48 @@
+package report
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSplitReportKeepsCollidingFileNames(t *testing.T) {
+	server := newFakeGitHub(t, nil)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.csv")
+	data := "Repository,Alert Number\na-b/c,1\na/b-c,2\na/b-c-2,3\n"
+	if err := os.WriteFile(input, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	output := filepath.Join(dir, "reports")
+
+	cfg := testConfig(server, input, output)
+	cfg.SplitBy = SplitByRepo
+	cfg.Columns = []string{"Owner", "Repo", "ID"}
+	if _, err := Generate(context.Background(), cfg); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// a-b/c and a/b-c share a name, and the suffix of the second must not
+	// take the name of a/b-c-2
+	want := map[string][]string{
+		"report-a-b-c.csv":   {"a-b", "c", "1"},
+		"report-a-b-c-2.csv": {"a", "b-c-2", "3"},
+		"report-a-b-c-3.csv": {"a", "b-c", "2"},
+	}
+	entries, err := os.ReadDir(output)
+	if err != nil {
+		t.Fatalf("failed to read output directory: %v", err)
+	}
+	if len(entries) != len(want) {
+		t.Errorf("wrote %d files, want %d", len(entries), len(want))
+	}
+	for name, wantRow := range want {
+		rows := readRows(t, filepath.Join(output, name))
+		if len(rows) != 1 || !slices.Equal(rows[0], wantRow) {
+			t.Errorf("%s has rows %q, want %q", name, rows, wantRow)
+		}
+	}
+}
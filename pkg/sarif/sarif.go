@@ -0,0 +1,195 @@
+// Package sarif provides functionality for rendering CodeQL alerts as a
+// SARIF 2.1.0 log.
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lindluni/gh-generate-codeql-report/pkg/codeql"
+)
+
+// Version and Schema identify the SARIF format that is written.
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Log is the top-level SARIF document.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run describes the results produced by a single tool.
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+// Tool describes the analysis tool that produced the results.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver describes the tool's primary component and the rules it ran.
+type Driver struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Rule describes a rule referenced by results.
+type Rule struct {
+	ID               string   `json:"id"`
+	ShortDescription *Message `json:"shortDescription,omitempty"`
+	FullDescription  *Message `json:"fullDescription,omitempty"`
+}
+
+// Result is a single finding.
+type Result struct {
+	RuleID     string                 `json:"ruleId,omitempty"`
+	Level      string                 `json:"level"`
+	Message    Message                `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// Message is a plain text message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Location is the location of a result.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a region within a file.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation identifies a file.
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is a range of lines and columns within a file.
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// Writer renders alerts as a SARIF log.
+type Writer struct {
+	out io.Writer
+}
+
+// NewWriter creates a new SARIF writer that writes to out.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{
+		out: out,
+	}
+}
+
+// WriteAll writes a SARIF log with a single run containing one result per alert.
+func (w *Writer) WriteAll(alerts []codeql.Alert) error {
+	encoder := json.NewEncoder(w.out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(NewLog(alerts)); err != nil {
+		return fmt.Errorf("failed to write SARIF: %w", err)
+	}
+	return nil
+}
+
+// NewLog builds a SARIF log from alerts.
+func NewLog(alerts []codeql.Alert) *Log {
+	run := Run{
+		Tool:    Tool{Driver: Driver{Name: "CodeQL"}},
+		Results: make([]Result, 0, len(alerts)),
+	}
+
+	seen := make(map[string]bool)
+	for _, alert := range alerts {
+		if alert.RuleID != "" && !seen[alert.RuleID] {
+			seen[alert.RuleID] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, newRule(alert))
+		}
+		run.Results = append(run.Results, newResult(alert))
+	}
+
+	return &Log{
+		Schema:  Schema,
+		Version: Version,
+		Runs:    []Run{run},
+	}
+}
+
+// newRule describes the rule that produced an alert.
+func newRule(alert codeql.Alert) Rule {
+	rule := Rule{ID: alert.RuleID}
+	if alert.ShortDesc != "" {
+		rule.ShortDescription = &Message{Text: alert.ShortDesc}
+	}
+	if alert.FullDesc != "" {
+		rule.FullDescription = &Message{Text: alert.FullDesc}
+	}
+	return rule
+}
+
+// newResult converts an alert into a SARIF result.
+func newResult(alert codeql.Alert) Result {
+	result := Result{
+		RuleID:  alert.RuleID,
+		Level:   Level(alert.Severity),
+		Message: Message{Text: alert.ShortDesc},
+		Properties: map[string]interface{}{
+			"repository":  alert.Owner + "/" + alert.Repo,
+			"alertNumber": alert.ID,
+		},
+	}
+	if alert.Severity != "" {
+		result.Properties["securitySeverityLevel"] = alert.Severity
+	}
+	if alert.HTMLURL != "" {
+		result.Properties["htmlUrl"] = alert.HTMLURL
+	}
+
+	if alert.FilePath != "" {
+		location := PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: alert.FilePath}}
+		// SARIF lines start at 1, so a zero line means the region is unknown
+		if alert.StartLine > 0 {
+			location.Region = &Region{
+				StartLine:   alert.StartLine,
+				StartColumn: alert.StartColumn,
+				EndLine:     alert.EndLine,
+				EndColumn:   alert.EndColumn,
+			}
+		}
+		result.Locations = []Location{{PhysicalLocation: location}}
+	}
+
+	return result
+}
+
+// Level maps a CodeQL security severity to a SARIF result level.
+func Level(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	case "low":
+		return "note"
+	default:
+		return "warning"
+	}
+}